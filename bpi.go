@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ErrBPINoKey is returned if no TEK is known for the SAID and key sequence of a frame.
+var ErrBPINoKey = errors.New("no bpi key for said and key sequence")
+
+// bpiCleartextSize is the size of the destination and source MAC addresses which aren't encrypted.
+const bpiCleartextSize = 12
+
+// BPI+ data encryption algorithms
+const (
+	BPIAlgorithmDES    = "des"
+	BPIAlgorithmAES128 = "aes128"
+)
+
+// BPIKey is a traffic encryption key of a security association.
+type BPIKey struct {
+	SAID        uint16
+	KeySequence uint8
+	Algorithm   string
+	Key         []byte
+	IV          []byte
+
+	block cipher.Block
+}
+
+type bpiKeyID struct {
+	said        uint16
+	keySequence uint8
+}
+
+// BPIDecryptor decrypts BPI+ encrypted frames with known TEKs.
+type BPIDecryptor struct {
+	keys map[bpiKeyID]*BPIKey
+	// latest holds the most recently added key per SAID and toggle bit
+	latest map[bpiKeyID]*BPIKey
+	buf    []byte
+}
+
+func newBPIDecryptor() *BPIDecryptor {
+	return &BPIDecryptor{
+		keys:   make(map[bpiKeyID]*BPIKey),
+		latest: make(map[bpiKeyID]*BPIKey),
+	}
+}
+
+// AddKey adds a TEK. The key sequence is the 4 bit sequence number of the key.
+func (decryptor *BPIDecryptor) AddKey(said uint16, keySequence uint8, algorithm string, key []byte, iv []byte) error {
+	var block cipher.Block
+	var err error
+
+	switch algorithm {
+	case BPIAlgorithmDES:
+		block, err = des.NewCipher(key)
+	case BPIAlgorithmAES128:
+		if len(key) != 16 {
+			return fmt.Errorf("aes128 key must be 16 bytes")
+		}
+		block, err = aes.NewCipher(key)
+	default:
+		return fmt.Errorf("bpi algorithm %q isn't supported", algorithm)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(iv) != block.BlockSize() {
+		return fmt.Errorf("bpi iv must be %d bytes", block.BlockSize())
+	}
+
+	bpiKey := &BPIKey{
+		SAID:        said,
+		KeySequence: keySequence & 0x0f,
+		Algorithm:   algorithm,
+		Key:         append([]byte(nil), key...),
+		IV:          append([]byte(nil), iv...),
+		block:       block,
+	}
+	decryptor.keys[bpiKeyID{said: said, keySequence: bpiKey.KeySequence}] = bpiKey
+	decryptor.latest[bpiKeyID{said: said, keySequence: bpiKey.KeySequence & 0x01}] = bpiKey
+
+	return nil
+}
+
+// LoadKeyFile adds the TEKs of a key file. Every line holds one key:
+//
+//	SAID keyseq des|aes128 keyhex ivhex
+//
+// Empty lines and lines starting with # are ignored.
+func (decryptor *BPIDecryptor) LoadKeyFile(reader io.Reader) error {
+	scanner := bufio.NewScanner(reader)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 5 {
+			return fmt.Errorf("key file line %d: expected 5 fields, got %d", lineNum, len(fields))
+		}
+
+		said, err := strconv.ParseUint(fields[0], 0, 14)
+		if err != nil {
+			return fmt.Errorf("key file line %d: invalid said: %w", lineNum, err)
+		}
+		keySequence, err := strconv.ParseUint(fields[1], 0, 4)
+		if err != nil {
+			return fmt.Errorf("key file line %d: invalid key sequence: %w", lineNum, err)
+		}
+		key, err := hex.DecodeString(fields[3])
+		if err != nil {
+			return fmt.Errorf("key file line %d: invalid key: %w", lineNum, err)
+		}
+		iv, err := hex.DecodeString(fields[4])
+		if err != nil {
+			return fmt.Errorf("key file line %d: invalid iv: %w", lineNum, err)
+		}
+
+		if err := decryptor.AddKey(uint16(said), uint8(keySequence), fields[2], key, iv); err != nil {
+			return fmt.Errorf("key file line %d: %w", lineNum, err)
+		}
+	}
+
+	return scanner.Err()
+}
+
+// lookup returns the key for the SAID and key sequence. If the sequence isn't known
+// the latest key with the same toggle bit is used.
+func (decryptor *BPIDecryptor) lookup(said uint16, keySequence uint8, toggle bool) (*BPIKey, bool) {
+	if key, ok := decryptor.keys[bpiKeyID{said: said, keySequence: keySequence}]; ok {
+		return key, true
+	}
+
+	parity := uint8(0)
+	if toggle {
+		parity = 1
+	}
+	key, ok := decryptor.latest[bpiKeyID{said: said, keySequence: parity}]
+	return key, ok
+}
+
+// Decrypt decrypts the frame following the MAC addresses. The returned slice is only valid
+// until the next call as its buffer is reused.
+func (decryptor *BPIDecryptor) Decrypt(said uint16, keySequence uint8, toggle bool, data []byte) ([]byte, error) {
+	key, ok := decryptor.lookup(said, keySequence, toggle)
+	if !ok {
+		return nil, ErrBPINoKey
+	}
+	if len(data) < bpiCleartextSize {
+		return nil, fmt.Errorf("bpi encrypted frame is too small")
+	}
+
+	decryptor.buf = append(decryptor.buf[:0], data...)
+	decryptCBCResidual(key.block, key.IV, decryptor.buf[bpiCleartextSize:], data[bpiCleartextSize:])
+
+	return decryptor.buf, nil
+}
+
+// decryptCBCResidual decrypts in CBC mode with residual block termination:
+// the final partial block is XORed with the encryption of the last full ciphertext block,
+// or of the IV if there is no full block.
+func decryptCBCResidual(block cipher.Block, iv []byte, dst []byte, src []byte) {
+	blockSize := block.BlockSize()
+	full := len(src) - len(src)%blockSize
+
+	if full > 0 {
+		cipher.NewCBCDecrypter(block, iv).CryptBlocks(dst[:full], src[:full])
+	}
+
+	if full < len(src) {
+		previous := iv
+		if full > 0 {
+			previous = src[full-blockSize : full]
+		}
+
+		keystream := make([]byte, blockSize)
+		block.Encrypt(keystream, previous)
+		for i := full; i < len(src); i++ {
+			dst[i] = src[i] ^ keystream[i-full]
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"strings"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// encryptCBCResidual is the encrypting counterpart of decryptCBCResidual.
+func encryptCBCResidual(block cipher.Block, iv []byte, src []byte) []byte {
+	blockSize := block.BlockSize()
+	full := len(src) - len(src)%blockSize
+	dst := make([]byte, len(src))
+
+	if full > 0 {
+		cipher.NewCBCEncrypter(block, iv).CryptBlocks(dst[:full], src[:full])
+	}
+
+	if full < len(src) {
+		previous := iv
+		if full > 0 {
+			previous = dst[full-blockSize : full]
+		}
+
+		keystream := make([]byte, blockSize)
+		block.Encrypt(keystream, previous)
+		for i := full; i < len(src); i++ {
+			dst[i] = src[i] ^ keystream[i-full]
+		}
+	}
+
+	return dst
+}
+
+func TestDecryptCBCResidual(t *testing.T) {
+	desBlock, err := des.NewCipher([]byte{0x13, 0x34, 0x57, 0x79, 0x9b, 0xbc, 0xdf, 0xf1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	aesBlock, err := aes.NewCipher(bytes.Repeat([]byte{0x11}, 16))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, block := range []cipher.Block{desBlock, aesBlock} {
+		iv := bytes.Repeat([]byte{0x22}, block.BlockSize())
+
+		for _, test := range []struct {
+			name   string
+			length int
+		}{
+			{"multiple of the block size", 4 * block.BlockSize()},
+			{"residual block", 4*block.BlockSize() + 5},
+			{"shorter than one block", 5},
+		} {
+			plaintext := make([]byte, test.length)
+			for i := range plaintext {
+				plaintext[i] = byte(i)
+			}
+			ciphertext := encryptCBCResidual(block, iv, plaintext)
+
+			decrypted := make([]byte, len(ciphertext))
+			decryptCBCResidual(block, iv, decrypted, ciphertext)
+			if !bytes.Equal(decrypted, plaintext) {
+				t.Errorf("block size %d, %s: decrypted %x, want %x", block.BlockSize(), test.name, decrypted, plaintext)
+			}
+		}
+
+		// a frame shorter than one block is XORed with the encrypted IV
+		keystream := make([]byte, block.BlockSize())
+		block.Encrypt(keystream, iv)
+		ciphertext := []byte{0x00, 0x01, 0x02}
+		decrypted := make([]byte, len(ciphertext))
+		decryptCBCResidual(block, iv, decrypted, ciphertext)
+		for i := range ciphertext {
+			if decrypted[i] != ciphertext[i]^keystream[i] {
+				t.Errorf("block size %d: short frame decrypted to %x", block.BlockSize(), decrypted)
+				break
+			}
+		}
+	}
+}
+
+func TestDOCSISDecryptsBPIFrame(t *testing.T) {
+	key := bytes.Repeat([]byte{0x11}, 16)
+	iv := bytes.Repeat([]byte{0x22}, 16)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decryptor := newBPIDecryptor()
+	if err := decryptor.LoadKeyFile(strings.NewReader("# test key\n\n0x123 3 aes128 " + strings.Repeat("11", 16) + " " + strings.Repeat("22", 16) + "\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	// the encrypted part after the MAC addresses is a multiple of the block size, has a residual block or is shorter than one block
+	for _, payloadLen := range []int{14, 37, 0} {
+		ethernet := make([]byte, 14+payloadLen)
+		copy(ethernet, []byte{0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x00, 0x01, 0x02, 0x03, 0x04, 0x06, 0x08, 0x00})
+		frame := withEthernetCRC(ethernet)
+		encrypted := append(append([]byte(nil), frame[:12]...), encryptCBCResidual(block, iv, frame[12:])...)
+
+		// BPI EH with key sequence 3, version 1, encrypted, toggle and SAID 0x123
+		ehdr := []byte{0x43, 0x31, 0xc1, 0x23}
+		docsis := &DOCSIS{Decryptor: decryptor}
+		ethernetLayer := &layers.Ethernet{}
+		parser := gopacket.NewDecodingLayerParser(LayerTypeDOCSIS, docsis, ethernetLayer)
+		parser.IgnoreUnsupported = true
+		var decoded []gopacket.LayerType
+		if err := parser.DecodeLayers(buildDOCSISFrame(0x01, ehdr, encrypted), &decoded); err != nil {
+			t.Fatalf("payload length %d: %v", payloadLen, err)
+		}
+		if !docsis.Decrypted || !docsis.PDUCRCCorrect || docsis.BPISAID != 0x123 || !docsis.BPIToggle || docsis.BPIKeySequence != 3 {
+			t.Errorf("payload length %d: decrypted %v, crc correct %v, said %#x", payloadLen, docsis.Decrypted, docsis.PDUCRCCorrect, docsis.BPISAID)
+		}
+		if len(decoded) != 2 || ethernetLayer.EthernetType != layers.EthernetTypeIPv4 {
+			t.Errorf("payload length %d: decoded %v with ethernet type %v", payloadLen, decoded, ethernetLayer.EthernetType)
+		}
+	}
+}
+
+func TestDOCSISWrongBPIKey(t *testing.T) {
+	block, err := aes.NewCipher(bytes.Repeat([]byte{0x11}, 16))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// only key sequence 1 is known, so key sequence 3 with the toggle bit falls back to it
+	decryptor := newBPIDecryptor()
+	if err := decryptor.AddKey(0x123, 1, BPIAlgorithmAES128, bytes.Repeat([]byte{0x33}, 16), bytes.Repeat([]byte{0x22}, 16)); err != nil {
+		t.Fatal(err)
+	}
+
+	ethernet := make([]byte, 60)
+	ethernet[12] = 0x08
+	frame := withEthernetCRC(ethernet)
+	encrypted := append(append([]byte(nil), frame[:12]...), encryptCBCResidual(block, bytes.Repeat([]byte{0x22}, 16), frame[12:])...)
+
+	docsis := &DOCSIS{Decryptor: decryptor}
+	if err := docsis.DecodeFromBytes(buildDOCSISFrame(0x01, []byte{0x43, 0x31, 0xc1, 0x23}, encrypted), nil); err != nil {
+		t.Fatal(err)
+	}
+	if docsis.Decrypted || docsis.PDUCRCCorrect {
+		t.Errorf("wrong key decrypted %v with crc correct %v", docsis.Decrypted, docsis.PDUCRCCorrect)
+	}
+	if !bytes.Equal(docsis.Payload, encrypted) {
+		t.Errorf("payload is %x, want the ciphertext %x", docsis.Payload, encrypted)
+	}
+	if next := docsis.NextLayerType(); next != LayerTypeETHENC {
+		t.Errorf("next layer is %v, want ETHENC", next)
+	}
+}
+
+func TestDOCSISShortBPIExtHdr(t *testing.T) {
+	ethernet := make([]byte, 60)
+	ethernet[12] = 0x08
+
+	// a BPI EH with only 2 value bytes can't hold the SAID
+	docsis := &DOCSIS{}
+	if err := docsis.DecodeFromBytes(buildDOCSISFrame(0x01, []byte{0x42, 0x31, 0xc1}, withEthernetCRC(ethernet)), nil); err != nil {
+		t.Fatal(err)
+	}
+	if docsis.Encrypted || docsis.BPISAID != 0 {
+		t.Errorf("short BPI EH decoded as encrypted %v with said %#x", docsis.Encrypted, docsis.BPISAID)
+	}
+}
+
+func TestBPIDecryptorLoadKeyFile(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		line string
+		err  string
+	}{
+		{"bad key hex", "1 0 des 01234567zz abcdef0123456789", "invalid key"},
+		{"bad iv hex", "1 0 des 0123456789abcdef abcdef012345678", "invalid iv"},
+		{"unknown cipher", "1 0 rc4 0123456789abcdef abcdef0123456789", "isn't supported"},
+		{"missing field", "1 0 des 0123456789abcdef", "expected 5 fields"},
+		{"said too large", "0x4000 0 des 0123456789abcdef abcdef0123456789", "invalid said"},
+		{"iv size", "1 0 aes128 " + strings.Repeat("11", 16) + " abcdef0123456789", "iv must be 16 bytes"},
+	} {
+		decryptor := newBPIDecryptor()
+		err := decryptor.LoadKeyFile(strings.NewReader("# keys\n" + test.line + "\n"))
+		if err == nil {
+			t.Errorf("%s: loaded without error", test.name)
+			continue
+		}
+		if !strings.Contains(err.Error(), "line 2") || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: error is %q, want line 2 and %q", test.name, err, test.err)
+		}
+	}
+
+	decryptor := newBPIDecryptor()
+	keyFile := "1 0 des 0123456789abcdef abcdef0123456789\n  # comment\n0x2 15 aes128 " + strings.Repeat("11", 16) + " " + strings.Repeat("22", 16) + "\n"
+	if err := decryptor.LoadKeyFile(strings.NewReader(keyFile)); err != nil {
+		t.Fatal(err)
+	}
+	if key, ok := decryptor.lookup(2, 15, true); !ok || key.Algorithm != BPIAlgorithmAES128 {
+		t.Errorf("key for said 2 sequence 15 is %+v", key)
+	}
+	if _, err := decryptor.Decrypt(3, 0, false, make([]byte, 20)); err != ErrBPINoKey {
+		t.Errorf("error for an unknown said is %v, want %v", err, ErrBPINoKey)
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// ErrBpkmHMACMismatch is returned if the HMAC digest of a BPKM message is wrong.
+var ErrBpkmHMACMismatch = errors.New("docsis bpkm hmac digest doesn't match")
+
+// bpkmHMACAttributeSize is the size of the HMAC digest attribute including its header.
+const bpkmHMACAttributeSize = 1 + 2 + sha1.Size
+
+// BPIAuthKeys are the keys derived from an authorization key.
+type BPIAuthKeys struct {
+	AuthKey []byte
+	// KEK is the two-key 3DES key encryption key (K1 | K2)
+	KEK []byte
+	// HMACKeyDown authenticates messages sent by the CMTS and HMACKeyUp those sent by the modem
+	HMACKeyDown []byte
+	HMACKeyUp   []byte
+}
+
+// DecryptAuthKey decrypts the authorization key of an Auth Reply with the private key of the modem.
+func DecryptAuthKey(privateKey *rsa.PrivateKey, encrypted []byte) ([]byte, error) {
+	return rsa.DecryptOAEP(sha1.New(), nil, privateKey, encrypted, nil)
+}
+
+// DeriveBPIAuthKeys derives the key encryption key and the HMAC keys from the authorization key.
+func DeriveBPIAuthKeys(authKey []byte) *BPIAuthKeys {
+	return &BPIAuthKeys{
+		AuthKey:     append([]byte(nil), authKey...),
+		KEK:         padHash(0x53, authKey)[:16],
+		HMACKeyDown: padHash(0x3a, authKey),
+		HMACKeyUp:   padHash(0x5c, authKey),
+	}
+}
+
+// padHash returns SHA-1 over 64 pad bytes followed by the authorization key.
+func padHash(pad byte, authKey []byte) []byte {
+	hash := sha1.New()
+	for i := 0; i < 64; i++ {
+		hash.Write([]byte{pad})
+	}
+	hash.Write(authKey)
+	return hash.Sum(nil)
+}
+
+// VerifyHMAC checks the HMAC digest of a BPKM message, which has to be its last attribute.
+// The message starts with the code and downstream selects the key of messages sent by the CMTS.
+func (keys *BPIAuthKeys) VerifyHMAC(message []byte, downstream bool) error {
+	if len(message) < 4+bpkmHMACAttributeSize {
+		return fmt.Errorf("docsis bpkm message is too small for a hmac digest")
+	}
+
+	digestStart := len(message) - bpkmHMACAttributeSize
+	if message[digestStart] != BpkmAttrHMACDigest {
+		return fmt.Errorf("docsis bpkm hmac digest isn't the last attribute")
+	}
+
+	key := keys.HMACKeyUp
+	if downstream {
+		key = keys.HMACKeyDown
+	}
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(message[:digestStart])
+	if !hmac.Equal(mac.Sum(nil), message[digestStart+3:]) {
+		return ErrBpkmHMACMismatch
+	}
+
+	return nil
+}
+
+// DecryptTEK decrypts a TEK of a Key Reply. DES keys are encrypted with 3DES EDE in ECB mode,
+// AES-128 keys with AES-128 in ECB mode, both with the key encryption key.
+func (keys *BPIAuthKeys) DecryptTEK(encrypted []byte) ([]byte, string, error) {
+	var block cipher.Block
+	var algorithm string
+	var err error
+
+	switch len(encrypted) {
+	case 8:
+		algorithm = BPIAlgorithmDES
+		block, err = des.NewTripleDESCipher(append(append([]byte(nil), keys.KEK...), keys.KEK[:8]...))
+	case 16:
+		algorithm = BPIAlgorithmAES128
+		block, err = aes.NewCipher(keys.KEK)
+	default:
+		return nil, "", fmt.Errorf("tek of length %d isn't supported", len(encrypted))
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	key := make([]byte, len(encrypted))
+	for i := 0; i < len(encrypted); i += block.BlockSize() {
+		block.Decrypt(key[i:i+block.BlockSize()], encrypted[i:i+block.BlockSize()])
+	}
+
+	return key, algorithm, nil
+}
+
+// KeyFileLine formats the key as a line of a key file read by BPIDecryptor.LoadKeyFile.
+func (key *BPIKey) KeyFileLine() string {
+	return fmt.Sprintf("%d %d %s %s %s", key.SAID, key.KeySequence, key.Algorithm, hex.EncodeToString(key.Key), hex.EncodeToString(key.IV))
+}
+
+// BPIKeyDeriver follows the BPKM exchanges of a modem whose private key is known
+// and derives its TEKs.
+type BPIKeyDeriver struct {
+	// OnKey is called for every decrypted TEK.
+	OnKey func(mac net.HardwareAddr, key BPIKey)
+
+	privateKey *rsa.PrivateKey
+	authKeys   map[string]*BPIAuthKeys
+}
+
+func newBPIKeyDeriver(privateKey *rsa.PrivateKey) *BPIKeyDeriver {
+	return &BPIKeyDeriver{
+		privateKey: privateKey,
+		authKeys:   make(map[string]*BPIAuthKeys),
+	}
+}
+
+// AddResponse processes a BPKM-RSP sent to the modem. Auth Replies that can't be decrypted
+// with the private key belong to other modems and are ignored.
+func (deriver *BPIKeyDeriver) AddResponse(mac net.HardwareAddr, rsp *DOCSISBpkmRsp) error {
+	switch rsp.Code {
+	case DocsisBpkmCodeAuthReply:
+		authKey, err := DecryptAuthKey(deriver.privateKey, rsp.AuthKey)
+		if err != nil {
+			return nil
+		}
+		deriver.authKeys[mac.String()] = DeriveBPIAuthKeys(authKey)
+	case DocsisBpkmCodeKeyReply:
+		keys, ok := deriver.authKeys[mac.String()]
+		if !ok {
+			return nil
+		}
+		if err := keys.VerifyHMAC(rsp.Contents, true); err != nil {
+			return err
+		}
+
+		for _, parameters := range rsp.TEKParameters {
+			tek, algorithm, err := keys.DecryptTEK(parameters.TEK)
+			if err != nil {
+				return err
+			}
+
+			if deriver.OnKey != nil {
+				deriver.OnKey(mac, BPIKey{
+					SAID:        rsp.SAID,
+					KeySequence: parameters.KeySequenceNumber & 0x0f,
+					Algorithm:   algorithm,
+					Key:         tek,
+					IV:          append([]byte(nil), parameters.CBCIV...),
+				})
+			}
+		}
+	}
+
+	return nil
+}
+
+// AuthKeys returns the keys derived for the modem.
+func (deriver *BPIKeyDeriver) AuthKeys(mac net.HardwareAddr) (*BPIAuthKeys, bool) {
+	keys, ok := deriver.authKeys[mac.String()]
+	return keys, ok
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/des"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"encoding/hex"
+	"net"
+	"testing"
+)
+
+// testAuthKey is the authorization key 00 01 .. 13
+var testAuthKey = []byte{
+	0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09,
+	0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10, 0x11, 0x12, 0x13,
+}
+
+func mustDecodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestDeriveBPIAuthKeys(t *testing.T) {
+	keys := DeriveBPIAuthKeys(testAuthKey)
+
+	// SHA-1 over 64 pad bytes of 0x53, 0x3a and 0x5c followed by the authorization key
+	if want := mustDecodeHex(t, "6fcc6584b48590b08e48975e0846b1d3"); !bytes.Equal(keys.KEK, want) {
+		t.Errorf("KEK is %x, want %x", keys.KEK, want)
+	}
+	if want := mustDecodeHex(t, "49102fc0a476c83a4ef2865ffd4626ae1609c819"); !bytes.Equal(keys.HMACKeyDown, want) {
+		t.Errorf("HMAC_KEY_D is %x, want %x", keys.HMACKeyDown, want)
+	}
+	if want := mustDecodeHex(t, "5914b352895b599a23f499078165e547ab213b96"); !bytes.Equal(keys.HMACKeyUp, want) {
+		t.Errorf("HMAC_KEY_U is %x, want %x", keys.HMACKeyUp, want)
+	}
+}
+
+func TestDecryptAuthKey(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := rsa.EncryptOAEP(sha1.New(), rand.Reader, &privateKey.PublicKey, testAuthKey, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authKey, err := DecryptAuthKey(privateKey, encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(authKey, testAuthKey) {
+		t.Errorf("authorization key is %x, want %x", authKey, testAuthKey)
+	}
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DecryptAuthKey(otherKey, encrypted); err == nil {
+		t.Error("authorization key was decrypted with the key of another modem")
+	}
+}
+
+func TestDecryptTEK(t *testing.T) {
+	keys := DeriveBPIAuthKeys(testAuthKey)
+
+	// 3DES EDE with K1 | K2 | K1 for DES keys
+	desTEK := []byte{0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef}
+	k1, err := des.NewCipher(keys.KEK[:8])
+	if err != nil {
+		t.Fatal(err)
+	}
+	k2, err := des.NewCipher(keys.KEK[8:16])
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted := make([]byte, 8)
+	k1.Encrypt(encrypted, desTEK)
+	k2.Decrypt(encrypted, encrypted)
+	k1.Encrypt(encrypted, encrypted)
+
+	tek, algorithm, err := keys.DecryptTEK(encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if algorithm != BPIAlgorithmDES || !bytes.Equal(tek, desTEK) {
+		t.Errorf("decrypted %s key %x, want %s key %x", algorithm, tek, BPIAlgorithmDES, desTEK)
+	}
+
+	// AES-128 ECB for AES keys
+	aesTEK := []byte{0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88, 0x99, 0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0xff}
+	block, err := aes.NewCipher(keys.KEK)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted = make([]byte, 16)
+	block.Encrypt(encrypted, aesTEK)
+
+	tek, algorithm, err = keys.DecryptTEK(encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if algorithm != BPIAlgorithmAES128 || !bytes.Equal(tek, aesTEK) {
+		t.Errorf("decrypted %s key %x, want %s key %x", algorithm, tek, BPIAlgorithmAES128, aesTEK)
+	}
+
+	if _, _, err := keys.DecryptTEK(make([]byte, 12)); err == nil {
+		t.Error("12 byte TEK decrypted without error")
+	}
+}
+
+// buildKeyReply returns a Key Reply frame whose HMAC digest is computed with the key.
+func buildKeyReply(t *testing.T, hmacKey []byte, attributes TLVs) []byte {
+	t.Helper()
+
+	rsp := &DOCSISBpkmRsp{}
+	rsp.Code = DocsisBpkmCodeKeyReply
+	rsp.Identifier = 2
+	rsp.Attributes = append(attributes, TLV{Type: BpkmAttrHMACDigest, Value: make([]byte, sha1.Size)})
+
+	// the digest covers the message up to the digest attribute, including the final length
+	frame, err := BuildManagementMessage(testCMTSMAC, testModemMAC, DocsisManagementBpkmRsp, 1, rsp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := &DOCSISBpkmRsp{}
+	decodeManagementMessage(t, frame, decoded)
+
+	mac := hmac.New(sha1.New, hmacKey)
+	mac.Write(decoded.Contents[:len(decoded.Contents)-bpkmHMACAttributeSize])
+	rsp.Attributes[len(rsp.Attributes)-1].Value = mac.Sum(nil)
+
+	frame, err = BuildManagementMessage(testCMTSMAC, testModemMAC, DocsisManagementBpkmRsp, 1, rsp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return frame
+}
+
+func TestBPIKeyDeriver(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encryptedAuthKey, err := rsa.EncryptOAEP(sha1.New(), rand.Reader, &privateKey.PublicKey, testAuthKey, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authReply := &DOCSISBpkmRsp{}
+	authReply.Code = DocsisBpkmCodeAuthReply
+	authReply.Identifier = 1
+	authReply.Attributes = TLVs{
+		{Type: BpkmAttrAuthKey, Value: encryptedAuthKey},
+		uint32TLV(BpkmAttrKeyLifetime, 604800),
+		uint8TLV(BpkmAttrKeySequenceNumber, 1),
+	}
+	authFrame, err := BuildManagementMessage(testCMTSMAC, testModemMAC, DocsisManagementBpkmRsp, 1, authReply)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys := DeriveBPIAuthKeys(testAuthKey)
+	tek := []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}
+	block, err := des.NewTripleDESCipher(append(append([]byte(nil), keys.KEK...), keys.KEK[:8]...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	encryptedTEK := make([]byte, 8)
+	block.Encrypt(encryptedTEK, tek)
+	iv := bytes.Repeat([]byte{0x09}, 8)
+
+	attributes := TLVs{
+		uint8TLV(BpkmAttrKeySequenceNumber, 1),
+		uint16TLV(BpkmAttrSAID, 0x55),
+		{Type: BpkmAttrTEKParameters, Children: TLVs{
+			{Type: BpkmAttrTEK, Value: encryptedTEK},
+			uint32TLV(BpkmAttrKeyLifetime, 43200),
+			uint8TLV(BpkmAttrKeySequenceNumber, 4),
+			{Type: BpkmAttrCBCIV, Value: iv},
+		}},
+	}
+	keyFrame := buildKeyReply(t, keys.HMACKeyDown, attributes)
+
+	deriver := newBPIKeyDeriver(privateKey)
+	var derived []BPIKey
+	deriver.OnKey = func(mac net.HardwareAddr, key BPIKey) {
+		derived = append(derived, key)
+	}
+	for _, frame := range [][]byte{authFrame, keyFrame} {
+		rsp := &DOCSISBpkmRsp{}
+		_, docsisManagement := decodeManagementMessage(t, frame, rsp)
+		if err := deriver.AddResponse(docsisManagement.DstMAC, rsp); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if authKeys, ok := deriver.AuthKeys(testModemMAC); !ok || !bytes.Equal(authKeys.AuthKey, testAuthKey) {
+		t.Errorf("authorization key of the modem is %v", authKeys)
+	}
+	if len(derived) != 1 {
+		t.Fatalf("derived keys are %+v, want 1", derived)
+	}
+	key := derived[0]
+	if key.SAID != 0x55 || key.KeySequence != 4 || key.Algorithm != BPIAlgorithmDES || !bytes.Equal(key.Key, tek) || !bytes.Equal(key.IV, iv) {
+		t.Errorf("derived key is %+v", key)
+	}
+
+	// a digest computed with the upstream key doesn't authenticate a message of the CMTS
+	rsp := &DOCSISBpkmRsp{}
+	decodeManagementMessage(t, buildKeyReply(t, keys.HMACKeyUp, attributes), rsp)
+	if err := keys.VerifyHMAC(rsp.Contents, true); err != ErrBpkmHMACMismatch {
+		t.Errorf("error is %v, want %v", err, ErrBpkmHMACMismatch)
+	}
+	if err := keys.VerifyHMAC(rsp.Contents, false); err != nil {
+		t.Errorf("upstream digest: %v", err)
+	}
+	if err := deriver.AddResponse(testModemMAC, rsp); err != ErrBpkmHMACMismatch {
+		t.Errorf("deriver error is %v, want %v", err, ErrBpkmHMACMismatch)
+	}
+}
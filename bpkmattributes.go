@@ -0,0 +1,240 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+)
+
+// BPKM attribute types
+const (
+	BpkmAttrSerialNumber           = 1
+	BpkmAttrManufacturerID         = 2
+	BpkmAttrMACAddress             = 3
+	BpkmAttrRSAPublicKey           = 4
+	BpkmAttrCMIdentification       = 5
+	BpkmAttrDisplayString          = 6
+	BpkmAttrAuthKey                = 7
+	BpkmAttrTEK                    = 8
+	BpkmAttrKeyLifetime            = 9
+	BpkmAttrKeySequenceNumber      = 10
+	BpkmAttrHMACDigest             = 11
+	BpkmAttrSAID                   = 12
+	BpkmAttrTEKParameters          = 13
+	BpkmAttrCBCIV                  = 15
+	BpkmAttrErrorCode              = 16
+	BpkmAttrCACertificate          = 17
+	BpkmAttrCMCertificate          = 18
+	BpkmAttrSecurityCapabilities   = 19
+	BpkmAttrCryptographicSuite     = 20
+	BpkmAttrCryptographicSuiteList = 21
+	BpkmAttrBPIVersion             = 22
+	BpkmAttrSADescriptor           = 23
+	BpkmAttrSAType                 = 24
+	BpkmAttrSAQuery                = 25
+	BpkmAttrSAQueryType            = 26
+	BpkmAttrIPAddress              = 27
+)
+
+// ErrBpkmLengthMismatch is returned if the attributes don't add up to the Length of the BPKM message.
+var ErrBpkmLengthMismatch = errors.New("docsis bpkm attributes don't match the length")
+
+// bpkmAttributesSchema describes the BPKM attributes which have a 2 byte length.
+var bpkmAttributesSchema = &TLVSchema{
+	LengthSize: 2,
+	Nested: map[uint8]*TLVSchema{
+		BpkmAttrCMIdentification:     nil,
+		BpkmAttrTEKParameters:        nil,
+		BpkmAttrSecurityCapabilities: nil,
+		BpkmAttrSADescriptor:         nil,
+		BpkmAttrSAQuery:              nil,
+	},
+}
+
+// TEKParameters are the traffic encryption key parameters of a Key Reply.
+// The TEK is encrypted with the key encryption key.
+type TEKParameters struct {
+	TEK               []byte
+	KeyLifetime       uint32
+	KeySequenceNumber uint8
+	CBCIV             []byte
+}
+
+// SADescriptor describes a security association.
+type SADescriptor struct {
+	SAID uint16
+	// SAType is 0 for primary, 1 for static and 2 for dynamic associations
+	SAType uint8
+	// CryptographicSuite is the data encryption algorithm in the high byte and the authentication algorithm in the low byte
+	CryptographicSuite uint16
+}
+
+// BpkmAttributes are the decoded attributes of a BPKM message.
+type BpkmAttributes struct {
+	MACAddress         net.HardwareAddr
+	AuthKey            []byte
+	KeyLifetime        uint32
+	KeySequenceNumber  uint8
+	HMACDigest         []byte
+	SAID               uint16
+	TEKParameters      []TEKParameters
+	ErrorCode          uint8
+	ErrorCodePresent   bool
+	DisplayString      string
+	CryptographicSuite uint16
+	BPIVersion         uint8
+	SADescriptors      []SADescriptor
+	CMIdentification   CMIdentification
+	CACertificate      []byte
+	CMCertificate      []byte
+	// SecurityCapabilities is only set in Auth Requests
+	SecurityCapabilities SecurityCapabilities
+	SAQueryType          uint8
+	SAQuery              TLVs
+	// Attributes holds all attributes including the ones not decoded into fields.
+	Attributes TLVs
+}
+
+// parseBpkmAttributes decodes the attributes of a BPKM message. The attributes have to fill length exactly.
+func (attrs *BpkmAttributes) parseBpkmAttributes(data []byte, length uint16) error {
+	*attrs = BpkmAttributes{}
+
+	if len(data) != int(length) {
+		return ErrBpkmLengthMismatch
+	}
+
+	tlvs, err := DecodeTLVs(data, bpkmAttributesSchema)
+	if err != nil {
+		return fmt.Errorf("docsis bpkm attributes: %w", err)
+	}
+
+	for _, tlv := range tlvs {
+		// nested attributes which couldn't be decoded
+		if tlv.Err != nil {
+			return fmt.Errorf("docsis bpkm attributes: %w", tlv.Err)
+		}
+
+		switch tlv.Type {
+		case BpkmAttrMACAddress:
+			if len(tlv.Value) != 6 {
+				err = fmt.Errorf("tlv %d has length %d instead of 6", tlv.Type, len(tlv.Value))
+			} else {
+				attrs.MACAddress = net.HardwareAddr(tlv.Value)
+			}
+		case BpkmAttrCMIdentification:
+			attrs.CMIdentification, err = parseCMIdentification(tlv.Children)
+		case BpkmAttrCACertificate:
+			attrs.CACertificate = tlv.Value
+		case BpkmAttrCMCertificate:
+			attrs.CMCertificate = tlv.Value
+		case BpkmAttrSecurityCapabilities:
+			attrs.SecurityCapabilities, err = parseSecurityCapabilities(tlv.Children)
+		case BpkmAttrSAQuery:
+			attrs.SAQuery = tlv.Children
+		case BpkmAttrSAQueryType:
+			attrs.SAQueryType, err = tlv.Uint8()
+		case BpkmAttrDisplayString:
+			attrs.DisplayString = string(tlv.Value)
+		case BpkmAttrAuthKey:
+			attrs.AuthKey = tlv.Value
+		case BpkmAttrKeyLifetime:
+			attrs.KeyLifetime, err = tlv.Uint32()
+		case BpkmAttrKeySequenceNumber:
+			attrs.KeySequenceNumber, err = tlv.Uint8()
+		case BpkmAttrHMACDigest:
+			if len(tlv.Value) != 20 {
+				err = fmt.Errorf("tlv %d has length %d instead of 20", tlv.Type, len(tlv.Value))
+			} else {
+				attrs.HMACDigest = tlv.Value
+			}
+		case BpkmAttrSAID:
+			attrs.SAID, err = tlv.Uint16()
+		case BpkmAttrTEKParameters:
+			var parameters TEKParameters
+			if parameters, err = parseTEKParameters(tlv.Children); err == nil {
+				attrs.TEKParameters = append(attrs.TEKParameters, parameters)
+			}
+		case BpkmAttrErrorCode:
+			attrs.ErrorCode, err = tlv.Uint8()
+			attrs.ErrorCodePresent = true
+		case BpkmAttrCryptographicSuite:
+			attrs.CryptographicSuite, err = tlv.Uint16()
+		case BpkmAttrBPIVersion:
+			attrs.BPIVersion, err = tlv.Uint8()
+		case BpkmAttrSADescriptor:
+			var descriptor SADescriptor
+			if descriptor, err = parseSADescriptor(tlv.Children); err == nil {
+				attrs.SADescriptors = append(attrs.SADescriptors, descriptor)
+			}
+		}
+
+		if err != nil {
+			return fmt.Errorf("docsis bpkm attributes: %w", err)
+		}
+	}
+
+	attrs.Attributes = tlvs
+
+	return nil
+}
+
+func parseTEKParameters(tlvs TLVs) (TEKParameters, error) {
+	parameters := TEKParameters{}
+
+	var err error
+	for _, tlv := range tlvs {
+		switch tlv.Type {
+		case BpkmAttrTEK:
+			parameters.TEK = tlv.Value
+		case BpkmAttrKeyLifetime:
+			parameters.KeyLifetime, err = tlv.Uint32()
+		case BpkmAttrKeySequenceNumber:
+			parameters.KeySequenceNumber, err = tlv.Uint8()
+		case BpkmAttrCBCIV:
+			parameters.CBCIV = tlv.Value
+		}
+
+		if err != nil {
+			return parameters, fmt.Errorf("tek parameters: %w", err)
+		}
+	}
+
+	return parameters, nil
+}
+
+func encodeTEKParameters(parameters TEKParameters) TLVs {
+	var tlvs TLVs
+	tlvs = appendBytesTLV(tlvs, BpkmAttrTEK, parameters.TEK)
+	tlvs = append(tlvs, uint32TLV(BpkmAttrKeyLifetime, parameters.KeyLifetime), uint8TLV(BpkmAttrKeySequenceNumber, parameters.KeySequenceNumber))
+	return appendBytesTLV(tlvs, BpkmAttrCBCIV, parameters.CBCIV)
+}
+
+func parseSADescriptor(tlvs TLVs) (SADescriptor, error) {
+	descriptor := SADescriptor{}
+
+	var err error
+	for _, tlv := range tlvs {
+		switch tlv.Type {
+		case BpkmAttrSAID:
+			descriptor.SAID, err = tlv.Uint16()
+		case BpkmAttrSAType:
+			descriptor.SAType, err = tlv.Uint8()
+		case BpkmAttrCryptographicSuite:
+			descriptor.CryptographicSuite, err = tlv.Uint16()
+		}
+
+		if err != nil {
+			return descriptor, fmt.Errorf("sa descriptor: %w", err)
+		}
+	}
+
+	return descriptor, nil
+}
+
+func encodeSADescriptor(descriptor SADescriptor) TLVs {
+	return TLVs{
+		uint16TLV(BpkmAttrSAID, descriptor.SAID),
+		uint8TLV(BpkmAttrSAType, descriptor.SAType),
+		uint16TLV(BpkmAttrCryptographicSuite, descriptor.CryptographicSuite),
+	}
+}
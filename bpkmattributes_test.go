@@ -0,0 +1,32 @@
+package main
+
+import "testing"
+
+func TestBpkmAttributesMalformedNested(t *testing.T) {
+	rsp := &DOCSISBpkmRsp{}
+	if err := rsp.DecodeFromBytes([]byte{0x08, 0x01, 0x00, 0x05, 0x0c, 0x00, 0x02, 0x00, 0x09}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if rsp.SAID != 9 {
+		t.Errorf("said is %d, want 9", rsp.SAID)
+	}
+
+	for _, test := range []struct {
+		name string
+		data []byte
+	}{
+		// the TEK attribute claims 5 bytes but the parameters are empty
+		{"tek parameters", []byte{0x08, 0x01, 0x00, 0x06, 0x0d, 0x00, 0x03, 0x08, 0x00, 0x05}},
+		{"sa descriptor", []byte{0x07, 0x01, 0x00, 0x05, 0x17, 0x00, 0x02, 0x0c, 0x00}},
+		{"cm identification", []byte{0x04, 0x01, 0x00, 0x04, 0x05, 0x00, 0x01, 0x01}},
+	} {
+		rsp := &DOCSISBpkmRsp{}
+		err := rsp.DecodeFromBytes(test.data, nil)
+		if err == nil {
+			t.Errorf("%s: decoded without error into %+v", test.name, rsp.BpkmAttributes)
+		}
+		if len(rsp.TEKParameters) != 0 || len(rsp.SADescriptors) != 0 {
+			t.Errorf("%s: corrupt attributes were appended", test.name)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"net"
+	"time"
+)
+
+// BpkmExchange is a BPKM request with its response.
+type BpkmExchange struct {
+	MAC          net.HardwareAddr
+	Identifier   byte
+	Request      BpkmCode
+	Response     BpkmCode
+	RequestTime  time.Time
+	ResponseTime time.Time
+	// Latency is the time between request and response, zero if the request wasn't answered
+	Latency time.Duration
+	// Answered is false for requests that timed out
+	Answered bool
+	// Rejected is set for Auth Reject, Key Reject and SA Map Reject responses
+	Rejected bool
+	// ErrorCode and DisplayString of a rejecting response
+	ErrorCode     uint8
+	DisplayString string
+}
+
+type bpkmKey struct {
+	mac        string
+	identifier byte
+}
+
+// BpkmCorrelator matches BPKM requests to responses by modem MAC and identifier.
+type BpkmCorrelator struct {
+	// Timeout after which a request without response is reported as unanswered.
+	Timeout time.Duration
+	// OnExchange is called for every answered request.
+	OnExchange func(exchange BpkmExchange)
+	// OnReject is called for answered requests that were rejected.
+	OnReject func(exchange BpkmExchange)
+	// OnUnanswered is called for requests without response by Expire.
+	OnUnanswered func(exchange BpkmExchange)
+
+	pending map[bpkmKey]*BpkmExchange
+}
+
+func newBpkmCorrelator() *BpkmCorrelator {
+	return &BpkmCorrelator{
+		Timeout: 10 * time.Second,
+		pending: make(map[bpkmKey]*BpkmExchange),
+	}
+}
+
+// AddRequest records a BPKM-REQ sent by the modem.
+// A retransmission with the same identifier keeps the time of the first request.
+func (correlator *BpkmCorrelator) AddRequest(mac net.HardwareAddr, req *DOCSISBpkmReq, captureTime time.Time) {
+	key := bpkmKey{mac: mac.String(), identifier: req.Identifier}
+	if _, ok := correlator.pending[key]; ok {
+		return
+	}
+
+	correlator.pending[key] = &BpkmExchange{
+		MAC:         append(net.HardwareAddr(nil), mac...),
+		Identifier:  req.Identifier,
+		Request:     req.Code,
+		RequestTime: captureTime,
+	}
+}
+
+// AddResponse records a BPKM-RSP sent to the modem. Responses without a matching
+// request, like the unsolicited Auth Invalid, are ignored.
+func (correlator *BpkmCorrelator) AddResponse(mac net.HardwareAddr, rsp *DOCSISBpkmRsp, captureTime time.Time) {
+	key := bpkmKey{mac: mac.String(), identifier: rsp.Identifier}
+	exchange, ok := correlator.pending[key]
+	if !ok {
+		return
+	}
+	delete(correlator.pending, key)
+
+	exchange.Response = rsp.Code
+	exchange.ResponseTime = captureTime
+	exchange.Latency = captureTime.Sub(exchange.RequestTime)
+	exchange.Answered = true
+
+	switch rsp.Code {
+	case DocsisBpkmCodeAuthReject, DocsisBpkmCodeKeyReject, DocsisBpkmCodeMapReject:
+		exchange.Rejected = true
+		exchange.ErrorCode = rsp.ErrorCode
+		exchange.DisplayString = rsp.DisplayString
+	}
+
+	if correlator.OnExchange != nil {
+		correlator.OnExchange(*exchange)
+	}
+	if exchange.Rejected && correlator.OnReject != nil {
+		correlator.OnReject(*exchange)
+	}
+}
+
+// Expire reports and removes the requests which weren't answered within the timeout.
+func (correlator *BpkmCorrelator) Expire(now time.Time) {
+	for key, exchange := range correlator.pending {
+		if now.Sub(exchange.RequestTime) < correlator.Timeout {
+			continue
+		}
+
+		delete(correlator.pending, key)
+		if correlator.OnUnanswered != nil {
+			correlator.OnUnanswered(*exchange)
+		}
+	}
+}
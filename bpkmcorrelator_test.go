@@ -0,0 +1,127 @@
+package main
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func bpkmRequest(code BpkmCode, identifier byte) *DOCSISBpkmReq {
+	req := &DOCSISBpkmReq{}
+	req.Code = code
+	req.Identifier = identifier
+	return req
+}
+
+func bpkmResponse(code BpkmCode, identifier byte) *DOCSISBpkmRsp {
+	rsp := &DOCSISBpkmRsp{}
+	rsp.Code = code
+	rsp.Identifier = identifier
+	return rsp
+}
+
+func TestBpkmCorrelatorMatching(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	otherModemMAC := net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x66}
+
+	correlator := newBpkmCorrelator()
+	var exchanges []BpkmExchange
+	correlator.OnExchange = func(exchange BpkmExchange) {
+		exchanges = append(exchanges, exchange)
+	}
+	correlator.OnReject = func(exchange BpkmExchange) {
+		t.Errorf("exchange %+v rejected", exchange)
+	}
+
+	correlator.AddRequest(testModemMAC, bpkmRequest(DocsisBpkmCodeAuthRequest, 1), start)
+	correlator.AddRequest(testModemMAC, bpkmRequest(DocsisBpkmCodeKeyRequest, 2), start.Add(time.Second))
+	// the same identifier of another modem is a different exchange
+	correlator.AddRequest(otherModemMAC, bpkmRequest(DocsisBpkmCodeKeyRequest, 1), start.Add(2*time.Second))
+	// a retransmission keeps the time of the first request
+	correlator.AddRequest(testModemMAC, bpkmRequest(DocsisBpkmCodeKeyRequest, 2), start.Add(3*time.Second))
+
+	// an unsolicited response doesn't match any request
+	correlator.AddResponse(testModemMAC, bpkmResponse(DocsisBpkmCodeAuthInvalid, 7), start.Add(4*time.Second))
+	correlator.AddResponse(testModemMAC, bpkmResponse(DocsisBpkmCodeKeyReply, 2), start.Add(5*time.Second))
+	correlator.AddResponse(otherModemMAC, bpkmResponse(DocsisBpkmCodeKeyReply, 1), start.Add(6*time.Second))
+	correlator.AddResponse(testModemMAC, bpkmResponse(DocsisBpkmCodeAuthReply, 1), start.Add(7*time.Second))
+
+	if len(exchanges) != 3 {
+		t.Fatalf("exchanges are %+v, want 3", exchanges)
+	}
+	want := []struct {
+		mac        net.HardwareAddr
+		identifier byte
+		request    BpkmCode
+		response   BpkmCode
+		latency    time.Duration
+	}{
+		{testModemMAC, 2, DocsisBpkmCodeKeyRequest, DocsisBpkmCodeKeyReply, 4 * time.Second},
+		{otherModemMAC, 1, DocsisBpkmCodeKeyRequest, DocsisBpkmCodeKeyReply, 4 * time.Second},
+		{testModemMAC, 1, DocsisBpkmCodeAuthRequest, DocsisBpkmCodeAuthReply, 7 * time.Second},
+	}
+	for i, w := range want {
+		got := exchanges[i]
+		if got.MAC.String() != w.mac.String() || got.Identifier != w.identifier || got.Request != w.request ||
+			got.Response != w.response || got.Latency != w.latency || !got.Answered || got.Rejected {
+			t.Errorf("exchange %d is %+v, want %+v", i, got, w)
+		}
+	}
+}
+
+func TestBpkmCorrelatorReject(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	correlator := newBpkmCorrelator()
+	var rejects []BpkmExchange
+	correlator.OnReject = func(exchange BpkmExchange) {
+		rejects = append(rejects, exchange)
+	}
+
+	correlator.AddRequest(testModemMAC, bpkmRequest(DocsisBpkmCodeKeyRequest, 1), start)
+	rsp := bpkmResponse(DocsisBpkmCodeKeyReject, 1)
+	rsp.ErrorCode = 4
+	rsp.DisplayString = "unauthorized SAID"
+	correlator.AddResponse(testModemMAC, rsp, start.Add(time.Second))
+
+	if len(rejects) != 1 {
+		t.Fatalf("rejects are %+v, want 1", rejects)
+	}
+	reject := rejects[0]
+	if !reject.Rejected || reject.ErrorCode != 4 || reject.DisplayString != "unauthorized SAID" || reject.Latency != time.Second {
+		t.Errorf("reject is %+v", reject)
+	}
+}
+
+func TestBpkmCorrelatorExpire(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	correlator := newBpkmCorrelator()
+	var unanswered []BpkmExchange
+	correlator.OnUnanswered = func(exchange BpkmExchange) {
+		unanswered = append(unanswered, exchange)
+	}
+	correlator.OnExchange = func(exchange BpkmExchange) {
+		t.Errorf("expired request answered with %+v", exchange)
+	}
+
+	correlator.AddRequest(testModemMAC, bpkmRequest(DocsisBpkmCodeKeyRequest, 1), start)
+	correlator.AddRequest(testModemMAC, bpkmRequest(DocsisBpkmCodeKeyRequest, 2), start.Add(5*time.Second))
+
+	correlator.Expire(start.Add(correlator.Timeout - time.Second))
+	if len(unanswered) != 0 {
+		t.Fatalf("unanswered are %+v before the timeout", unanswered)
+	}
+
+	correlator.Expire(start.Add(correlator.Timeout))
+	if len(unanswered) != 1 || unanswered[0].Identifier != 1 || unanswered[0].Answered || unanswered[0].Latency != 0 {
+		t.Fatalf("unanswered are %+v, want identifier 1", unanswered)
+	}
+
+	// the expired request is gone, a late response isn't matched anymore
+	correlator.AddResponse(testModemMAC, bpkmResponse(DocsisBpkmCodeKeyReply, 1), start.Add(correlator.Timeout+time.Second))
+	correlator.Expire(start.Add(correlator.Timeout))
+	if len(unanswered) != 1 {
+		t.Errorf("unanswered are %+v, want only identifier 1", unanswered)
+	}
+}
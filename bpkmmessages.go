@@ -0,0 +1,409 @@
+package main
+
+import (
+	"fmt"
+	"net"
+)
+
+// BpkmCode is the code of a BPKM message.
+type BpkmCode uint8
+
+// DocsisBpkmCodeAuthRequest code for Auth Request
+const DocsisBpkmCodeAuthRequest = 4
+
+// DocsisBpkmCodeAuthReply code for Auth Reply
+const DocsisBpkmCodeAuthReply = 5
+
+// DocsisBpkmCodeAuthReject code for Auth Reject
+const DocsisBpkmCodeAuthReject = 6
+
+// DocsisBpkmCodeKeyRequest code for Key Request
+const DocsisBpkmCodeKeyRequest = 7
+
+// DocsisBpkmCodeKeyReply code for Key Reply
+const DocsisBpkmCodeKeyReply = 8
+
+// DocsisBpkmCodeKeyReject code for Key Reject
+const DocsisBpkmCodeKeyReject = 9
+
+// DocsisBpkmCodeAuthInvalid code for Auth Invalid
+const DocsisBpkmCodeAuthInvalid = 10
+
+// DocsisBpkmCodeTEKInvalid code for TEK Invalid
+const DocsisBpkmCodeTEKInvalid = 11
+
+// DocsisBpkmCodeAuthInfo code for Authentication Information
+const DocsisBpkmCodeAuthInfo = 12
+
+// DocsisBpkmCodeMapRequest code for SA Map Request
+const DocsisBpkmCodeMapRequest = 13
+
+// DocsisBpkmCodeMapReply code for SA Map Reply
+const DocsisBpkmCodeMapReply = 14
+
+// DocsisBpkmCodeMapReject code for SA Map Reject
+const DocsisBpkmCodeMapReject = 15
+
+var bpkmCodeNames = map[BpkmCode]string{
+	DocsisBpkmCodeAuthRequest: "Auth Request",
+	DocsisBpkmCodeAuthReply:   "Auth Reply",
+	DocsisBpkmCodeAuthReject:  "Auth Reject",
+	DocsisBpkmCodeKeyRequest:  "Key Request",
+	DocsisBpkmCodeKeyReply:    "Key Reply",
+	DocsisBpkmCodeKeyReject:   "Key Reject",
+	DocsisBpkmCodeAuthInvalid: "Auth Invalid",
+	DocsisBpkmCodeTEKInvalid:  "TEK Invalid",
+	DocsisBpkmCodeAuthInfo:    "Auth Info",
+	DocsisBpkmCodeMapRequest:  "SA Map Request",
+	DocsisBpkmCodeMapReply:    "SA Map Reply",
+	DocsisBpkmCodeMapReject:   "SA Map Reject",
+}
+
+func (code BpkmCode) String() string {
+	if name, ok := bpkmCodeNames[code]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown code %d", uint8(code))
+}
+
+// BpkmMessage is the decoded content of a BPKM message of a specific code.
+type BpkmMessage interface {
+	Code() BpkmCode
+}
+
+// BpkmAuthRequest is sent by the modem to request an authorization key.
+type BpkmAuthRequest struct {
+	CMIdentification     CMIdentification
+	CMCertificate        []byte
+	SecurityCapabilities SecurityCapabilities
+	SAID                 uint16
+}
+
+// BpkmAuthReply carries the authorization key encrypted with the public key of the modem.
+type BpkmAuthReply struct {
+	AuthKey           []byte
+	KeyLifetime       uint32
+	KeySequenceNumber uint8
+	SADescriptors     []SADescriptor
+}
+
+// BpkmAuthReject is sent if the CMTS refuses the authorization.
+type BpkmAuthReject struct {
+	ErrorCode     uint8
+	DisplayString string
+}
+
+// BpkmKeyRequest is sent by the modem to request the TEKs of a security association.
+type BpkmKeyRequest struct {
+	KeySequenceNumber uint8
+	SAID              uint16
+	HMACDigest        []byte
+}
+
+// BpkmKeyReply carries the old and new TEK of a security association.
+type BpkmKeyReply struct {
+	KeySequenceNumber uint8
+	SAID              uint16
+	TEKParameters     []TEKParameters
+	HMACDigest        []byte
+}
+
+// BpkmKeyReject is sent if the CMTS refuses a key request.
+type BpkmKeyReject struct {
+	KeySequenceNumber uint8
+	SAID              uint16
+	ErrorCode         uint8
+	DisplayString     string
+	HMACDigest        []byte
+}
+
+// BpkmAuthInvalid tells the modem to reauthorize.
+type BpkmAuthInvalid struct {
+	ErrorCode     uint8
+	DisplayString string
+}
+
+// BpkmTEKInvalid tells the modem that a TEK is invalid.
+type BpkmTEKInvalid struct {
+	KeySequenceNumber uint8
+	SAID              uint16
+	ErrorCode         uint8
+	DisplayString     string
+	HMACDigest        []byte
+}
+
+// BpkmAuthInfo carries the certificate of the manufacturer CA.
+type BpkmAuthInfo struct {
+	CACertificate []byte
+}
+
+// BpkmMapRequest asks the CMTS for the security association of a multicast group.
+type BpkmMapRequest struct {
+	CMIdentification CMIdentification
+	SAQueryType      uint8
+	SAQuery          TLVs
+}
+
+// BpkmMapReply maps a multicast group to a security association.
+type BpkmMapReply struct {
+	SADescriptors []SADescriptor
+}
+
+// BpkmMapReject is sent if the CMTS refuses a SA map request.
+type BpkmMapReject struct {
+	ErrorCode     uint8
+	DisplayString string
+}
+
+// Code returns DocsisBpkmCodeAuthRequest
+func (msg *BpkmAuthRequest) Code() BpkmCode { return DocsisBpkmCodeAuthRequest }
+
+// Code returns DocsisBpkmCodeAuthReply
+func (msg *BpkmAuthReply) Code() BpkmCode { return DocsisBpkmCodeAuthReply }
+
+// Code returns DocsisBpkmCodeAuthReject
+func (msg *BpkmAuthReject) Code() BpkmCode { return DocsisBpkmCodeAuthReject }
+
+// Code returns DocsisBpkmCodeKeyRequest
+func (msg *BpkmKeyRequest) Code() BpkmCode { return DocsisBpkmCodeKeyRequest }
+
+// Code returns DocsisBpkmCodeKeyReply
+func (msg *BpkmKeyReply) Code() BpkmCode { return DocsisBpkmCodeKeyReply }
+
+// Code returns DocsisBpkmCodeKeyReject
+func (msg *BpkmKeyReject) Code() BpkmCode { return DocsisBpkmCodeKeyReject }
+
+// Code returns DocsisBpkmCodeAuthInvalid
+func (msg *BpkmAuthInvalid) Code() BpkmCode { return DocsisBpkmCodeAuthInvalid }
+
+// Code returns DocsisBpkmCodeTEKInvalid
+func (msg *BpkmTEKInvalid) Code() BpkmCode { return DocsisBpkmCodeTEKInvalid }
+
+// Code returns DocsisBpkmCodeAuthInfo
+func (msg *BpkmAuthInfo) Code() BpkmCode { return DocsisBpkmCodeAuthInfo }
+
+// Code returns DocsisBpkmCodeMapRequest
+func (msg *BpkmMapRequest) Code() BpkmCode { return DocsisBpkmCodeMapRequest }
+
+// Code returns DocsisBpkmCodeMapReply
+func (msg *BpkmMapReply) Code() BpkmCode { return DocsisBpkmCodeMapReply }
+
+// Code returns DocsisBpkmCodeMapReject
+func (msg *BpkmMapReject) Code() BpkmCode { return DocsisBpkmCodeMapReject }
+
+// newBpkmMessage returns the message of the code filled from the decoded attributes, nil for unknown codes.
+func newBpkmMessage(code BpkmCode, attrs *BpkmAttributes) BpkmMessage {
+	switch code {
+	case DocsisBpkmCodeAuthRequest:
+		return &BpkmAuthRequest{
+			CMIdentification:     attrs.CMIdentification,
+			CMCertificate:        attrs.CMCertificate,
+			SecurityCapabilities: attrs.SecurityCapabilities,
+			SAID:                 attrs.SAID,
+		}
+	case DocsisBpkmCodeAuthReply:
+		return &BpkmAuthReply{
+			AuthKey:           attrs.AuthKey,
+			KeyLifetime:       attrs.KeyLifetime,
+			KeySequenceNumber: attrs.KeySequenceNumber,
+			SADescriptors:     attrs.SADescriptors,
+		}
+	case DocsisBpkmCodeAuthReject:
+		return &BpkmAuthReject{ErrorCode: attrs.ErrorCode, DisplayString: attrs.DisplayString}
+	case DocsisBpkmCodeKeyRequest:
+		return &BpkmKeyRequest{
+			KeySequenceNumber: attrs.KeySequenceNumber,
+			SAID:              attrs.SAID,
+			HMACDigest:        attrs.HMACDigest,
+		}
+	case DocsisBpkmCodeKeyReply:
+		return &BpkmKeyReply{
+			KeySequenceNumber: attrs.KeySequenceNumber,
+			SAID:              attrs.SAID,
+			TEKParameters:     attrs.TEKParameters,
+			HMACDigest:        attrs.HMACDigest,
+		}
+	case DocsisBpkmCodeKeyReject:
+		return &BpkmKeyReject{
+			KeySequenceNumber: attrs.KeySequenceNumber,
+			SAID:              attrs.SAID,
+			ErrorCode:         attrs.ErrorCode,
+			DisplayString:     attrs.DisplayString,
+			HMACDigest:        attrs.HMACDigest,
+		}
+	case DocsisBpkmCodeAuthInvalid:
+		return &BpkmAuthInvalid{ErrorCode: attrs.ErrorCode, DisplayString: attrs.DisplayString}
+	case DocsisBpkmCodeTEKInvalid:
+		return &BpkmTEKInvalid{
+			KeySequenceNumber: attrs.KeySequenceNumber,
+			SAID:              attrs.SAID,
+			ErrorCode:         attrs.ErrorCode,
+			DisplayString:     attrs.DisplayString,
+			HMACDigest:        attrs.HMACDigest,
+		}
+	case DocsisBpkmCodeAuthInfo:
+		return &BpkmAuthInfo{CACertificate: attrs.CACertificate}
+	case DocsisBpkmCodeMapRequest:
+		return &BpkmMapRequest{
+			CMIdentification: attrs.CMIdentification,
+			SAQueryType:      attrs.SAQueryType,
+			SAQuery:          attrs.SAQuery,
+		}
+	case DocsisBpkmCodeMapReply:
+		return &BpkmMapReply{SADescriptors: attrs.SADescriptors}
+	case DocsisBpkmCodeMapReject:
+		return &BpkmMapReject{ErrorCode: attrs.ErrorCode, DisplayString: attrs.DisplayString}
+	}
+
+	return nil
+}
+
+// encodeBpkmMessage returns the attributes of the message, the reverse of newBpkmMessage.
+// Byte and string attributes are left out if they are empty, the HMAC digest is always the last attribute.
+func encodeBpkmMessage(message BpkmMessage) TLVs {
+	var tlvs TLVs
+	var displayString string
+	var hmacDigest []byte
+
+	switch msg := message.(type) {
+	case *BpkmAuthRequest:
+		tlvs = append(tlvs, TLV{Type: BpkmAttrCMIdentification, Children: encodeCMIdentification(msg.CMIdentification)})
+		tlvs = appendBytesTLV(tlvs, BpkmAttrCMCertificate, msg.CMCertificate)
+		tlvs = append(tlvs,
+			TLV{Type: BpkmAttrSecurityCapabilities, Children: encodeSecurityCapabilities(msg.SecurityCapabilities)},
+			uint16TLV(BpkmAttrSAID, msg.SAID))
+	case *BpkmAuthReply:
+		tlvs = appendBytesTLV(tlvs, BpkmAttrAuthKey, msg.AuthKey)
+		tlvs = append(tlvs, uint32TLV(BpkmAttrKeyLifetime, msg.KeyLifetime), uint8TLV(BpkmAttrKeySequenceNumber, msg.KeySequenceNumber))
+		for _, descriptor := range msg.SADescriptors {
+			tlvs = append(tlvs, TLV{Type: BpkmAttrSADescriptor, Children: encodeSADescriptor(descriptor)})
+		}
+	case *BpkmAuthReject:
+		tlvs = append(tlvs, uint8TLV(BpkmAttrErrorCode, msg.ErrorCode))
+		displayString = msg.DisplayString
+	case *BpkmKeyRequest:
+		tlvs = append(tlvs, uint8TLV(BpkmAttrKeySequenceNumber, msg.KeySequenceNumber), uint16TLV(BpkmAttrSAID, msg.SAID))
+		hmacDigest = msg.HMACDigest
+	case *BpkmKeyReply:
+		tlvs = append(tlvs, uint8TLV(BpkmAttrKeySequenceNumber, msg.KeySequenceNumber), uint16TLV(BpkmAttrSAID, msg.SAID))
+		for _, parameters := range msg.TEKParameters {
+			tlvs = append(tlvs, TLV{Type: BpkmAttrTEKParameters, Children: encodeTEKParameters(parameters)})
+		}
+		hmacDigest = msg.HMACDigest
+	case *BpkmKeyReject:
+		tlvs = append(tlvs, uint8TLV(BpkmAttrKeySequenceNumber, msg.KeySequenceNumber), uint16TLV(BpkmAttrSAID, msg.SAID),
+			uint8TLV(BpkmAttrErrorCode, msg.ErrorCode))
+		displayString = msg.DisplayString
+		hmacDigest = msg.HMACDigest
+	case *BpkmAuthInvalid:
+		tlvs = append(tlvs, uint8TLV(BpkmAttrErrorCode, msg.ErrorCode))
+		displayString = msg.DisplayString
+	case *BpkmTEKInvalid:
+		tlvs = append(tlvs, uint8TLV(BpkmAttrKeySequenceNumber, msg.KeySequenceNumber), uint16TLV(BpkmAttrSAID, msg.SAID),
+			uint8TLV(BpkmAttrErrorCode, msg.ErrorCode))
+		displayString = msg.DisplayString
+		hmacDigest = msg.HMACDigest
+	case *BpkmAuthInfo:
+		tlvs = appendBytesTLV(tlvs, BpkmAttrCACertificate, msg.CACertificate)
+	case *BpkmMapRequest:
+		tlvs = append(tlvs,
+			TLV{Type: BpkmAttrCMIdentification, Children: encodeCMIdentification(msg.CMIdentification)},
+			uint8TLV(BpkmAttrSAQueryType, msg.SAQueryType),
+			TLV{Type: BpkmAttrSAQuery, Children: msg.SAQuery})
+	case *BpkmMapReply:
+		for _, descriptor := range msg.SADescriptors {
+			tlvs = append(tlvs, TLV{Type: BpkmAttrSADescriptor, Children: encodeSADescriptor(descriptor)})
+		}
+	case *BpkmMapReject:
+		tlvs = append(tlvs, uint8TLV(BpkmAttrErrorCode, msg.ErrorCode))
+		displayString = msg.DisplayString
+	}
+
+	tlvs = appendBytesTLV(tlvs, BpkmAttrDisplayString, []byte(displayString))
+	return appendBytesTLV(tlvs, BpkmAttrHMACDigest, hmacDigest)
+}
+
+// appendBytesTLV appends the value as TLV unless it is empty.
+func appendBytesTLV(tlvs TLVs, tlvType uint8, value []byte) TLVs {
+	if len(value) == 0 {
+		return tlvs
+	}
+	return append(tlvs, TLV{Type: tlvType, Value: value})
+}
+
+// CMIdentification identifies the modem in authorization and SA map requests.
+type CMIdentification struct {
+	SerialNumber   string
+	ManufacturerID []byte
+	MACAddress     net.HardwareAddr
+	RSAPublicKey   []byte
+}
+
+// SecurityCapabilities are the BPI+ capabilities of the modem.
+type SecurityCapabilities struct {
+	CryptographicSuites []uint16
+	BPIVersion          uint8
+}
+
+func parseCMIdentification(tlvs TLVs) (CMIdentification, error) {
+	identification := CMIdentification{}
+
+	for _, tlv := range tlvs {
+		switch tlv.Type {
+		case BpkmAttrSerialNumber:
+			identification.SerialNumber = string(tlv.Value)
+		case BpkmAttrManufacturerID:
+			identification.ManufacturerID = tlv.Value
+		case BpkmAttrMACAddress:
+			if len(tlv.Value) != 6 {
+				return identification, fmt.Errorf("cm identification: tlv %d has length %d instead of 6", tlv.Type, len(tlv.Value))
+			}
+			identification.MACAddress = net.HardwareAddr(tlv.Value)
+		case BpkmAttrRSAPublicKey:
+			identification.RSAPublicKey = tlv.Value
+		}
+	}
+
+	return identification, nil
+}
+
+func encodeCMIdentification(identification CMIdentification) TLVs {
+	var tlvs TLVs
+	tlvs = appendBytesTLV(tlvs, BpkmAttrSerialNumber, []byte(identification.SerialNumber))
+	tlvs = appendBytesTLV(tlvs, BpkmAttrManufacturerID, identification.ManufacturerID)
+	tlvs = appendBytesTLV(tlvs, BpkmAttrMACAddress, identification.MACAddress)
+	return appendBytesTLV(tlvs, BpkmAttrRSAPublicKey, identification.RSAPublicKey)
+}
+
+func parseSecurityCapabilities(tlvs TLVs) (SecurityCapabilities, error) {
+	capabilities := SecurityCapabilities{}
+
+	var err error
+	for _, tlv := range tlvs {
+		switch tlv.Type {
+		case BpkmAttrCryptographicSuiteList:
+			if len(tlv.Value)%2 != 0 {
+				return capabilities, fmt.Errorf("security capabilities: tlv %d length isn't a multiple of 2", tlv.Type)
+			}
+			for i := 0; i < len(tlv.Value); i += 2 {
+				capabilities.CryptographicSuites = append(capabilities.CryptographicSuites, uint16(tlv.Value[i])<<8|uint16(tlv.Value[i+1]))
+			}
+		case BpkmAttrBPIVersion:
+			capabilities.BPIVersion, err = tlv.Uint8()
+		}
+
+		if err != nil {
+			return capabilities, fmt.Errorf("security capabilities: %w", err)
+		}
+	}
+
+	return capabilities, nil
+}
+
+func encodeSecurityCapabilities(capabilities SecurityCapabilities) TLVs {
+	var suites []byte
+	for _, suite := range capabilities.CryptographicSuites {
+		suites = append(suites, byte(suite>>8), byte(suite))
+	}
+	return TLVs{{Type: BpkmAttrCryptographicSuiteList, Value: suites}, uint8TLV(BpkmAttrBPIVersion, capabilities.BPIVersion)}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"net"
+	"sort"
+)
+
+// ChannelChangeKind is the kind of message that changed the channel assignment.
+type ChannelChangeKind uint8
+
+const (
+	// ChannelChangeRegistration is the initial assignment of the REG-RSP
+	ChannelChangeRegistration ChannelChangeKind = iota
+	// ChannelChangeUCC is an upstream channel change
+	ChannelChangeUCC
+	// ChannelChangeDCC is a dynamic channel change
+	ChannelChangeDCC
+	// ChannelChangeDBC is a dynamic bonding change
+	ChannelChangeDBC
+)
+
+// ChannelAssignment are the channels a modem is known to use.
+type ChannelAssignment struct {
+	UpstreamChannelIDs    []uint8
+	DownstreamFrequencies []uint32
+	// DownstreamChannelID is only known after a DCC
+	DownstreamChannelID uint8
+}
+
+// ChannelChangeEvent is emitted whenever the channel assignment of a modem changes.
+type ChannelChangeEvent struct {
+	MAC           net.HardwareAddr
+	Kind          ChannelChangeKind
+	TransactionID uint16
+	Assignment    ChannelAssignment
+}
+
+type channelChangeKey struct {
+	mac           string
+	kind          ChannelChangeKind
+	transactionID uint16
+}
+
+type channelChange struct {
+	upstreamChannelActions []UpstreamChannelAction
+	downstreamFrequencies  []uint32
+	downstreamChannelID    uint8
+}
+
+type channelState struct {
+	upstreams   map[uint8]bool
+	downstreams map[uint32]bool
+	downstream  uint8
+}
+
+// ChannelAssignmentTracker follows the channel assignment of every modem through UCC, DCC and DBC.
+type ChannelAssignmentTracker struct {
+	// OnChange is called after the assignment of a modem changed.
+	OnChange func(event ChannelChangeEvent)
+
+	modems  map[string]*channelState
+	pending map[channelChangeKey]*channelChange
+}
+
+func newChannelAssignmentTracker() *ChannelAssignmentTracker {
+	return &ChannelAssignmentTracker{
+		modems:  make(map[string]*channelState),
+		pending: make(map[channelChangeKey]*channelChange),
+	}
+}
+
+func (tracker *ChannelAssignmentTracker) state(mac net.HardwareAddr) *channelState {
+	state, ok := tracker.modems[mac.String()]
+	if !ok {
+		state = &channelState{upstreams: make(map[uint8]bool), downstreams: make(map[uint32]bool)}
+		tracker.modems[mac.String()] = state
+	}
+	return state
+}
+
+// SetRegistration sets the initial assignment from the channel configuration of the REG-RSP.
+func (tracker *ChannelAssignmentTracker) SetRegistration(mac net.HardwareAddr, encodings *DOCSISRegEncodings) {
+	actions, frequencies, err := parseChannelConfiguration(encodings.TLVs)
+	if err != nil || (len(actions) == 0 && len(frequencies) == 0) {
+		return
+	}
+
+	state := tracker.state(mac)
+	state.upstreams = make(map[uint8]bool)
+	state.downstreams = make(map[uint32]bool)
+	tracker.apply(mac, ChannelChangeRegistration, 0, &channelChange{upstreamChannelActions: actions, downstreamFrequencies: frequencies})
+}
+
+// AddUCCRequest applies the upstream channel change to the modem.
+// There is no acknowledgement so the change is applied right away.
+func (tracker *ChannelAssignmentTracker) AddUCCRequest(mac net.HardwareAddr, ucc *DOCSISBaseUCC) {
+	state := tracker.state(mac)
+	state.upstreams = make(map[uint8]bool)
+	tracker.apply(mac, ChannelChangeUCC, 0, &channelChange{
+		upstreamChannelActions: []UpstreamChannelAction{{Action: UpstreamChannelActionAdd, ChannelID: ucc.UpstreamChannelID}},
+	})
+}
+
+// AddDCCRequest records a DCC-REQ sent to the modem.
+func (tracker *ChannelAssignmentTracker) AddDCCRequest(mac net.HardwareAddr, dcc *DOCSISBaseDCC) {
+	change := &channelChange{downstreamChannelID: dcc.DownstreamChannelID}
+	if dcc.UpstreamChannelID != 0 {
+		change.upstreamChannelActions = []UpstreamChannelAction{{Action: UpstreamChannelActionReplace, NewChannelID: dcc.UpstreamChannelID}}
+	}
+	if dcc.DownstreamFrequency != 0 {
+		change.downstreamFrequencies = []uint32{dcc.DownstreamFrequency}
+	}
+	tracker.pending[channelChangeKey{mac: mac.String(), kind: ChannelChangeDCC, transactionID: dcc.TransactionID}] = change
+}
+
+// AddDBCRequest records a DBC-REQ fragment sent to the modem.
+func (tracker *ChannelAssignmentTracker) AddDBCRequest(mac net.HardwareAddr, dbc *DOCSISBaseDBC) {
+	key := channelChangeKey{mac: mac.String(), kind: ChannelChangeDBC, transactionID: dbc.TransactionID}
+	change, ok := tracker.pending[key]
+	if !ok {
+		change = &channelChange{}
+		tracker.pending[key] = change
+	}
+	change.upstreamChannelActions = append(change.upstreamChannelActions, dbc.UpstreamChannelActions...)
+	change.downstreamFrequencies = append(change.downstreamFrequencies, dbc.DownstreamFrequencies...)
+}
+
+// AddConfirmation applies a pending DCC or DBC once the response or acknowledgement confirms it.
+func (tracker *ChannelAssignmentTracker) AddConfirmation(mac net.HardwareAddr, kind ChannelChangeKind, transactionID uint16, confirmationCode uint8) {
+	key := channelChangeKey{mac: mac.String(), kind: kind, transactionID: transactionID}
+	change, ok := tracker.pending[key]
+	if !ok {
+		return
+	}
+	delete(tracker.pending, key)
+
+	// DCC-RSP uses depart (180) and arrive (181) to confirm the change
+	if confirmationCode != 0 && !(kind == ChannelChangeDCC && (confirmationCode == 180 || confirmationCode == 181)) {
+		return
+	}
+
+	state := tracker.state(mac)
+	if kind == ChannelChangeDCC {
+		// a DCC moves the modem to a single new channel
+		if len(change.upstreamChannelActions) > 0 {
+			state.upstreams = make(map[uint8]bool)
+		}
+		if len(change.downstreamFrequencies) > 0 {
+			state.downstreams = make(map[uint32]bool)
+		}
+	} else if len(change.downstreamFrequencies) > 0 {
+		// the receive channel configuration of a DBC lists all downstream channels
+		state.downstreams = make(map[uint32]bool)
+	}
+
+	tracker.apply(mac, kind, transactionID, change)
+}
+
+func (tracker *ChannelAssignmentTracker) apply(mac net.HardwareAddr, kind ChannelChangeKind, transactionID uint16, change *channelChange) {
+	state := tracker.state(mac)
+
+	for _, action := range change.upstreamChannelActions {
+		switch action.Action {
+		case UpstreamChannelActionDelete:
+			delete(state.upstreams, action.ChannelID)
+		case UpstreamChannelActionReplace:
+			delete(state.upstreams, action.ChannelID)
+			state.upstreams[action.NewChannelID] = true
+		default:
+			state.upstreams[action.ChannelID] = true
+		}
+	}
+	for _, frequency := range change.downstreamFrequencies {
+		state.downstreams[frequency] = true
+	}
+	if change.downstreamChannelID != 0 {
+		state.downstream = change.downstreamChannelID
+	}
+
+	if tracker.OnChange != nil {
+		tracker.OnChange(ChannelChangeEvent{
+			MAC:           append(net.HardwareAddr(nil), mac...),
+			Kind:          kind,
+			TransactionID: transactionID,
+			Assignment:    state.assignment(),
+		})
+	}
+}
+
+func (state *channelState) assignment() ChannelAssignment {
+	assignment := ChannelAssignment{DownstreamChannelID: state.downstream}
+	for channelID := range state.upstreams {
+		assignment.UpstreamChannelIDs = append(assignment.UpstreamChannelIDs, channelID)
+	}
+	for frequency := range state.downstreams {
+		assignment.DownstreamFrequencies = append(assignment.DownstreamFrequencies, frequency)
+	}
+	sort.Slice(assignment.UpstreamChannelIDs, func(i, j int) bool {
+		return assignment.UpstreamChannelIDs[i] < assignment.UpstreamChannelIDs[j]
+	})
+	sort.Slice(assignment.DownstreamFrequencies, func(i, j int) bool {
+		return assignment.DownstreamFrequencies[i] < assignment.DownstreamFrequencies[j]
+	})
+	return assignment
+}
+
+// Assignment returns the known channel assignment of a modem.
+func (tracker *ChannelAssignmentTracker) Assignment(mac net.HardwareAddr) (ChannelAssignment, bool) {
+	state, ok := tracker.modems[mac.String()]
+	if !ok {
+		return ChannelAssignment{}, false
+	}
+	return state.assignment(), true
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"net"
+	"time"
+)
+
+// CMStatusEvent is a single event reported by a modem through CM-STATUS.
+type CMStatusEvent struct {
+	Time                 time.Time
+	Node                 string
+	MAC                  net.HardwareAddr
+	TransactionID        uint16
+	EventType            CMStatusEventType
+	DownstreamChannelIDs []uint8
+	UpstreamChannelIDs   []uint8
+}
+
+// CMStatusStorm is reported when many modems of a node send CM-STATUS messages within a short time.
+type CMStatusStorm struct {
+	Node      string
+	Start     time.Time
+	Modems    int
+	Events    int
+	EventType map[CMStatusEventType]int
+}
+
+type cmStatusNode struct {
+	events  []CMStatusEvent
+	inStorm bool
+}
+
+// CMStatusMonitor turns CM-STATUS messages into events and detects storms per node.
+// A node is any string the caller uses to group modems, for example the CMTS MAC address or the downstream.
+type CMStatusMonitor struct {
+	// Window is the time span in which events are counted for storm detection.
+	Window time.Duration
+	// StormThreshold is the number of distinct modems reporting within the window that is considered a storm.
+	StormThreshold int
+	// OnEvent is called for every CM-STATUS event, retransmissions within the window aren't reported again.
+	OnEvent func(event CMStatusEvent)
+	// OnStorm is called once when a storm starts. It's reported again after the node calmed down.
+	OnStorm func(storm CMStatusStorm)
+
+	nodes map[string]*cmStatusNode
+}
+
+func newCMStatusMonitor() *CMStatusMonitor {
+	return &CMStatusMonitor{
+		Window:         time.Minute,
+		StormThreshold: 10,
+		nodes:          make(map[string]*cmStatusNode),
+	}
+}
+
+// Add records a CM-STATUS message sent by the modem.
+func (monitor *CMStatusMonitor) Add(node string, mac net.HardwareAddr, status *DOCSISCMStatus, captureTime time.Time) {
+	event := CMStatusEvent{
+		Time:                 captureTime,
+		Node:                 node,
+		MAC:                  append(net.HardwareAddr(nil), mac...),
+		TransactionID:        status.TransactionID,
+		EventType:            status.EventType,
+		DownstreamChannelIDs: append([]uint8(nil), status.DownstreamChannelIDs...),
+		UpstreamChannelIDs:   append([]uint8(nil), status.UpstreamChannelIDs...),
+	}
+
+	state, ok := monitor.nodes[node]
+	if !ok {
+		state = &cmStatusNode{}
+		monitor.nodes[node] = state
+	}
+	monitor.expire(state, captureTime)
+
+	// modems retransmit CM-STATUS with the same transaction ID until it's acknowledged
+	for _, previous := range state.events {
+		if previous.MAC.String() == event.MAC.String() && previous.TransactionID == event.TransactionID && previous.EventType == event.EventType {
+			return
+		}
+	}
+
+	if monitor.OnEvent != nil {
+		monitor.OnEvent(event)
+	}
+
+	state.events = append(state.events, event)
+
+	storm := monitor.summarize(node, state)
+	if storm.Modems < monitor.StormThreshold {
+		state.inStorm = false
+		return
+	}
+
+	if !state.inStorm {
+		state.inStorm = true
+		if monitor.OnStorm != nil {
+			monitor.OnStorm(storm)
+		}
+	}
+}
+
+// expire drops the events that are older than the window.
+func (monitor *CMStatusMonitor) expire(state *cmStatusNode, now time.Time) {
+	deadline := now.Add(-monitor.Window)
+	i := 0
+	for i < len(state.events) && state.events[i].Time.Before(deadline) {
+		i++
+	}
+	state.events = state.events[i:]
+}
+
+func (monitor *CMStatusMonitor) summarize(node string, state *cmStatusNode) CMStatusStorm {
+	storm := CMStatusStorm{Node: node, Events: len(state.events), EventType: make(map[CMStatusEventType]int)}
+	modems := make(map[string]bool)
+	for _, event := range state.events {
+		modems[event.MAC.String()] = true
+		storm.EventType[event.EventType]++
+	}
+	if len(state.events) > 0 {
+		storm.Start = state.events[0].Time
+	}
+	storm.Modems = len(modems)
+	return storm
+}
+
+// Recent returns the events of a node within the window.
+func (monitor *CMStatusMonitor) Recent(node string) []CMStatusEvent {
+	state, ok := monitor.nodes[node]
+	if !ok {
+		return nil
+	}
+	return append([]CMStatusEvent(nil), state.events...)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestCMStatusMonitorRetransmissions(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	status := &DOCSISCMStatus{TransactionID: 7, EventType: CMStatusQAMFECLockFailure}
+
+	monitor := newCMStatusMonitor()
+	var events []CMStatusEvent
+	monitor.OnEvent = func(event CMStatusEvent) {
+		events = append(events, event)
+	}
+
+	monitor.Add("node", testModemMAC, status, start)
+	monitor.Add("node", testModemMAC, status, start.Add(time.Second))
+	if len(events) != 1 {
+		t.Fatalf("events are %+v, want the retransmission to be dropped", events)
+	}
+
+	// once the first report left the window the same transaction is a new event
+	monitor.Add("node", testModemMAC, status, start.Add(monitor.Window+time.Second))
+	if len(events) != 2 {
+		t.Fatalf("events are %+v, want the report after the window", events)
+	}
+	if recent := monitor.Recent("node"); len(recent) != 1 || !recent[0].Time.Equal(start.Add(monitor.Window+time.Second)) {
+		t.Errorf("recent events are %+v, want only the last report", recent)
+	}
+}
+
+func TestCMStatusMonitorStorm(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	status := &DOCSISCMStatus{TransactionID: 1, EventType: CMStatusSecondaryChannelMDDTimeout}
+
+	monitor := newCMStatusMonitor()
+	monitor.StormThreshold = 3
+	var storms []CMStatusStorm
+	monitor.OnStorm = func(storm CMStatusStorm) {
+		storms = append(storms, storm)
+	}
+
+	for i := 0; i < 4; i++ {
+		mac := net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, byte(i)}
+		monitor.Add("node", mac, status, start.Add(time.Duration(i)*time.Second))
+		// retransmissions don't count as additional events
+		monitor.Add("node", mac, status, start.Add(time.Duration(i)*time.Second))
+	}
+
+	if len(storms) != 1 {
+		t.Fatalf("storms are %+v, want 1", storms)
+	}
+	if storms[0].Modems != 3 || storms[0].Events != 3 || !storms[0].Start.Equal(start) {
+		t.Errorf("storm is %+v, want 3 modems and events starting at %v", storms[0], start)
+	}
+}
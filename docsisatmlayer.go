@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// LayerTypeDOCSISATM type registration
+var LayerTypeDOCSISATM = gopacket.RegisterLayerType(1007, gopacket.LayerTypeMetadata{Name: "DOCSIS ATM PDU", Decoder: gopacket.DecodeFunc(decodeDOCSISATM)})
+
+// atmCellSize is the size of an ATM cell including its 5 byte header
+const atmCellSize = 53
+
+// ATMCell is a single ATM cell (UNI format) of an ATM PDU.
+type ATMCell struct {
+	GFC     uint8
+	VPI     uint8
+	VCI     uint16
+	PTI     uint8
+	CLP     bool
+	HEC     uint8
+	Payload []byte
+}
+
+// DOCSISATM is a DOCSIS ATM PDU consisting of a sequence of ATM cells.
+type DOCSISATM struct {
+	layers.BaseLayer
+	Cells []ATMCell
+}
+
+// LayerType returns LayerTypeDOCSISATM
+func (docsis *DOCSISATM) LayerType() gopacket.LayerType {
+	return LayerTypeDOCSISATM
+}
+
+// DecodeFromBytes decodes the given bytes into this layer.
+func (docsis *DOCSISATM) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
+	if len(data)%atmCellSize != 0 {
+		return fmt.Errorf("docsis atm pdu length isn't a multiple of the cell size")
+	}
+
+	docsis.Cells = docsis.Cells[:0]
+
+	for i := 0; i < len(data); i += atmCellSize {
+		cell := data[i : i+atmCellSize]
+		header := binary.BigEndian.Uint32(cell[0:4])
+
+		docsis.Cells = append(docsis.Cells, ATMCell{
+			GFC:     uint8(header >> 28),
+			VPI:     uint8(header >> 20),
+			VCI:     uint16(header >> 4),
+			PTI:     uint8(header>>1) & 0x07,
+			CLP:     (header & 0x01) == 1,
+			HEC:     cell[4],
+			Payload: cell[5:],
+		})
+	}
+
+	docsis.Contents = data[:]
+	docsis.Payload = data[:0]
+
+	return nil
+}
+
+// CanDecode returns the set of layer types that this DecodingLayer can decode.
+func (docsis *DOCSISATM) CanDecode() gopacket.LayerClass {
+	return LayerTypeDOCSISATM
+}
+
+// NextLayerType returns the layer type contained by this DecodingLayer.
+func (docsis *DOCSISATM) NextLayerType() gopacket.LayerType {
+	return gopacket.LayerTypePayload
+}
+
+func decodeDOCSISATM(data []byte, p gopacket.PacketBuilder) error {
+	docsis := &DOCSISATM{}
+	err := docsis.DecodeFromBytes(data, p)
+	if err != nil {
+		return err
+	}
+	p.AddLayer(docsis)
+
+	return p.NextDecoder(docsis.NextLayerType())
+}
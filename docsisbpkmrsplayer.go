@@ -11,15 +11,81 @@ import (
 // LayerTypeDOCSISBpkmRsp type registration
 var LayerTypeDOCSISBpkmRsp = gopacket.RegisterLayerType(1003, gopacket.LayerTypeMetadata{Name: "DOCSIS Management Privacy Key Management Response", Decoder: gopacket.DecodeFunc(decodeDOCSISBpkmRsp)})
 
-// DocsisBpkmCodeKeyReply code for Key Reply
-const DocsisBpkmCodeKeyReply = 8
+// LayerTypeDOCSISBpkmReq type registration
+var LayerTypeDOCSISBpkmReq = gopacket.RegisterLayerType(1042, gopacket.LayerTypeMetadata{Name: "DOCSIS Management Privacy Key Management Request", Decoder: gopacket.DecodeFunc(decodeDOCSISBpkmReq)})
+
+func init() {
+	RegisterManagementType(DocsisManagementBpkmRsp, ManagementVersionAny, LayerTypeDOCSISBpkmRsp, func() gopacket.DecodingLayer { return &DOCSISBpkmRsp{} })
+	RegisterManagementType(DocsisManagementBpkmReq, ManagementVersionAny, LayerTypeDOCSISBpkmReq, func() gopacket.DecodingLayer { return &DOCSISBpkmReq{} })
+}
+
+// DOCSISBaseBpkm are the fields shared by BPKM-REQ and BPKM-RSP.
+type DOCSISBaseBpkm struct {
+	Code       BpkmCode
+	Identifier byte
+	Length     uint16
+	BpkmAttributes
+	// Message holds the attributes relevant for the code, nil for unknown codes
+	Message BpkmMessage
+}
+
+func (docsis *DOCSISBaseBpkm) parseBpkm(data []byte) error {
+	if len(data) < 4 {
+		return fmt.Errorf("docsis bpkm packet is too small for the header")
+	}
+
+	docsis.Code = BpkmCode(data[0])
+	docsis.Identifier = data[1]
+	docsis.Length = binary.BigEndian.Uint16(data[2:4])
+
+	if err := docsis.parseBpkmAttributes(data[4:], docsis.Length); err != nil {
+		return err
+	}
+	docsis.Message = newBpkmMessage(docsis.Code, &docsis.BpkmAttributes)
+
+	return nil
+}
+
+// serializeBpkm prepends the BPKM message. The attributes are encoded from Attributes, without them
+// they are generated from Message or, if Message is nil, from the typed fields the code carries.
+func (docsis *DOCSISBaseBpkm) serializeBpkm(b gopacket.SerializeBuffer, opts gopacket.SerializeOptions) error {
+	tlvs := docsis.Attributes
+	if len(tlvs) == 0 {
+		message := docsis.Message
+		if message == nil {
+			message = newBpkmMessage(docsis.Code, &docsis.BpkmAttributes)
+		} else if message.Code() != docsis.Code {
+			return fmt.Errorf("docsis bpkm message is a %v, but the code is %v", message.Code(), docsis.Code)
+		}
+		tlvs = encodeBpkmMessage(message)
+	}
+
+	attributes, err := tlvs.Encode(bpkmAttributesSchema)
+	if err != nil {
+		return err
+	}
+
+	bytes, err := b.PrependBytes(4 + len(attributes))
+	if err != nil {
+		return err
+	}
+
+	if opts.FixLengths {
+		docsis.Length = uint16(len(attributes))
+	}
+
+	bytes[0] = byte(docsis.Code)
+	bytes[1] = docsis.Identifier
+	binary.BigEndian.PutUint16(bytes[2:4], docsis.Length)
+	copy(bytes[4:], attributes)
+
+	return nil
+}
 
 // DOCSISBpkmRsp is a DOCSIS Management packet header.
 type DOCSISBpkmRsp struct {
 	layers.BaseLayer
-	Code       byte
-	Identifier byte
-	Length     uint16
+	DOCSISBaseBpkm
 }
 
 // LayerType returns LayerTypeDOCSISBpkmRsp
@@ -29,20 +95,21 @@ func (docsis *DOCSISBpkmRsp) LayerType() gopacket.LayerType {
 
 // DecodeFromBytes decodes the given bytes into this layer.
 func (docsis *DOCSISBpkmRsp) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
-	if len(data) < 4 {
-		return fmt.Errorf("docsis bpkm resp packet is too small for the header")
+	if err := docsis.parseBpkm(data); err != nil {
+		return err
 	}
 
-	docsis.Code = data[0]
-	docsis.Identifier = data[1]
-	docsis.Length = binary.BigEndian.Uint16(data[2:4])
-
 	docsis.Contents = data[:]
 	docsis.Payload = data[:0]
 
 	return nil
 }
 
+// SerializeTo writes the serialized form of this layer into the SerializationBuffer.
+func (docsis *DOCSISBpkmRsp) SerializeTo(b gopacket.SerializeBuffer, opts gopacket.SerializeOptions) error {
+	return docsis.serializeBpkm(b, opts)
+}
+
 // CanDecode returns the set of layer types that this DecodingLayer can decode.
 func (docsis *DOCSISBpkmRsp) CanDecode() gopacket.LayerClass {
 	return LayerTypeDOCSISBpkmRsp
@@ -63,3 +130,52 @@ func decodeDOCSISBpkmRsp(data []byte, p gopacket.PacketBuilder) error {
 
 	return p.NextDecoder(docsis.NextLayerType())
 }
+
+// DOCSISBpkmReq is a DOCSIS Management Privacy Key Management Request.
+type DOCSISBpkmReq struct {
+	layers.BaseLayer
+	DOCSISBaseBpkm
+}
+
+// LayerType returns LayerTypeDOCSISBpkmReq
+func (docsis *DOCSISBpkmReq) LayerType() gopacket.LayerType {
+	return LayerTypeDOCSISBpkmReq
+}
+
+// DecodeFromBytes decodes the given bytes into this layer.
+func (docsis *DOCSISBpkmReq) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
+	if err := docsis.parseBpkm(data); err != nil {
+		return err
+	}
+
+	docsis.Contents = data[:]
+	docsis.Payload = data[:0]
+
+	return nil
+}
+
+// SerializeTo writes the serialized form of this layer into the SerializationBuffer.
+func (docsis *DOCSISBpkmReq) SerializeTo(b gopacket.SerializeBuffer, opts gopacket.SerializeOptions) error {
+	return docsis.serializeBpkm(b, opts)
+}
+
+// CanDecode returns the set of layer types that this DecodingLayer can decode.
+func (docsis *DOCSISBpkmReq) CanDecode() gopacket.LayerClass {
+	return LayerTypeDOCSISBpkmReq
+}
+
+// NextLayerType returns the layer type contained by this DecodingLayer.
+func (docsis *DOCSISBpkmReq) NextLayerType() gopacket.LayerType {
+	return gopacket.LayerTypePayload
+}
+
+func decodeDOCSISBpkmReq(data []byte, p gopacket.PacketBuilder) error {
+	docsis := &DOCSISBpkmReq{}
+	err := docsis.DecodeFromBytes(data, p)
+	if err != nil {
+		return err
+	}
+	p.AddLayer(docsis)
+
+	return p.NextDecoder(docsis.NextLayerType())
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"net"
+
+	"github.com/google/gopacket"
+)
+
+// BuildManagementMessage serializes a management message into a complete DOCSIS frame.
+// The message length, LLC header, CRC and HCS are filled in.
+func BuildManagementMessage(src, dst net.HardwareAddr, messageType uint8, version uint8, message gopacket.SerializableLayer) ([]byte, error) {
+	docsis := &DOCSIS{FCType: 3, FCParm: 1}
+	switch messageType {
+	case DocsisManagementSync, DocsisManagementRngReq, DocsisManagementBInitRngReq:
+		// these are sent with a timing header
+		docsis.FCParm = 0
+	}
+
+	docsisManagement := &DOCSISManagement{
+		SrcMAC:  src,
+		DstMAC:  dst,
+		Version: version,
+		Type:    messageType,
+	}
+
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+	if err := gopacket.SerializeLayers(buf, opts, docsis, docsisManagement, message); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	"github.com/google/gopacket"
+)
+
+var (
+	testCMTSMAC  = net.HardwareAddr{0x00, 0x01, 0x5c, 0x00, 0x00, 0x01}
+	testModemMAC = net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}
+)
+
+func decodeManagementMessage(t *testing.T, data []byte, message gopacket.DecodingLayer) (*DOCSIS, *DOCSISManagement) {
+	t.Helper()
+
+	docsis := &DOCSIS{}
+	docsisManagement := &DOCSISManagement{}
+	parser := gopacket.NewDecodingLayerParser(LayerTypeDOCSIS, docsis, docsisManagement, message)
+	var decoded []gopacket.LayerType
+	if err := parser.DecodeLayers(data, &decoded); err != nil {
+		t.Fatalf("decoding %x: %v (decoded %v)", data, err, decoded)
+	}
+	if len(decoded) != 3 {
+		t.Fatalf("decoded layers are %v, want 3", decoded)
+	}
+	if !docsis.CheckSequenceCorrect {
+		t.Error("header check sequence is wrong")
+	}
+
+	return docsis, docsisManagement
+}
+
+func TestBuildManagementMessageSync(t *testing.T) {
+	data, err := BuildManagementMessage(testCMTSMAC, net.HardwareAddr{0x01, 0xe0, 0x2f, 0x00, 0x00, 0x01}, DocsisManagementSync, 1, &DOCSISSync{Timestamp: 0x12345678})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sync := &DOCSISSync{}
+	docsis, docsisManagement := decodeManagementMessage(t, data, sync)
+	if docsis.FCParm != 0 {
+		t.Errorf("SYNC was sent with FC_PARM %d, want the timing header", docsis.FCParm)
+	}
+	if int(docsis.Length) != len(data)-6 {
+		t.Errorf("docsis length is %d, want %d", docsis.Length, len(data)-6)
+	}
+	if docsisManagement.Type != DocsisManagementSync || !bytes.Equal(docsisManagement.SrcMAC, testCMTSMAC) {
+		t.Errorf("management header is %+v", docsisManagement)
+	}
+	if sync.Timestamp != 0x12345678 {
+		t.Errorf("timestamp is %#x, want 0x12345678", sync.Timestamp)
+	}
+}
+
+func TestBuildManagementMessageTimingHeader(t *testing.T) {
+	for _, messageType := range []uint8{DocsisManagementSync, DocsisManagementRngReq, DocsisManagementBInitRngReq} {
+		data, err := BuildManagementMessage(testModemMAC, testCMTSMAC, messageType, 1, gopacket.Payload{0x00, 0x01, 0x02, 0x03})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fcParm := (data[0] & 0x3e) >> 1; fcParm != 0 {
+			t.Errorf("message type %d was sent with FC_PARM %d, want the timing header", messageType, fcParm)
+		}
+	}
+
+	data, err := BuildManagementMessage(testCMTSMAC, testModemMAC, DocsisManagementRegRsp, 1, gopacket.Payload{0x00, 0x01, 0x00})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fcParm := (data[0] & 0x3e) >> 1; fcParm != 1 {
+		t.Errorf("REG-RSP was sent with FC_PARM %d, want 1", fcParm)
+	}
+}
+
+func TestBuildManagementMessageRegRsp(t *testing.T) {
+	rsp := &DOCSISRegRsp{}
+	rsp.Sid = 0x1234
+	rsp.TLVs = TLVs{{Type: 5, Children: TLVs{uint8TLV(2, 3)}}}
+	rsp.ServiceFlows = []ServiceFlow{
+		{Upstream: true, Reference: 1, SFID: 7, ServiceClassName: "gold", MaxSustainedRate: 1000000},
+		{Reference: 2, SFID: 8, MaxSustainedRate: 5000000},
+	}
+
+	data, err := BuildManagementMessage(testCMTSMAC, testModemMAC, DocsisManagementRegRsp, 1, rsp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded := &DOCSISRegRsp{}
+	decodeManagementMessage(t, data, decoded)
+	if decoded.Sid != 0x1234 || decoded.Response != 0 || decoded.DocsisVersion != 3 {
+		t.Errorf("decoded %+v", decoded.DOCSISBaseRegRsp)
+	}
+	if decoded.UpstreamMaxRate != 1000000 || decoded.DownstreamMaxRate != 5000000 {
+		t.Errorf("rates are %d/%d, want 1000000/5000000", decoded.UpstreamMaxRate, decoded.DownstreamMaxRate)
+	}
+	if len(decoded.ServiceFlows) != 2 {
+		t.Fatalf("service flows are %+v, want 2", decoded.ServiceFlows)
+	}
+	for i, want := range rsp.ServiceFlows {
+		got := decoded.ServiceFlows[i]
+		if got.Upstream != want.Upstream || got.Reference != want.Reference || got.SFID != want.SFID ||
+			got.ServiceClassName != want.ServiceClassName || got.MaxSustainedRate != want.MaxSustainedRate {
+			t.Errorf("service flow %d is %+v, want %+v", i, got, want)
+		}
+	}
+}
+
+func TestBuildManagementMessageBpkmRsp(t *testing.T) {
+	tek := []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}
+	rsp := &DOCSISBpkmRsp{}
+	rsp.Code = DocsisBpkmCodeKeyReply
+	rsp.Identifier = 3
+	rsp.Attributes = TLVs{
+		uint8TLV(BpkmAttrKeySequenceNumber, 2),
+		uint16TLV(BpkmAttrSAID, 0x2001),
+		{Type: BpkmAttrTEKParameters, Children: TLVs{{Type: BpkmAttrTEK, Value: tek}}},
+	}
+
+	data, err := BuildManagementMessage(testCMTSMAC, testModemMAC, DocsisManagementBpkmRsp, 1, rsp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded := &DOCSISBpkmRsp{}
+	decodeManagementMessage(t, data, decoded)
+	if decoded.Code != DocsisBpkmCodeKeyReply || decoded.Identifier != 3 {
+		t.Errorf("decoded code %v identifier %d", decoded.Code, decoded.Identifier)
+	}
+	if decoded.KeySequenceNumber != 2 || decoded.SAID != 0x2001 {
+		t.Errorf("key sequence %d SAID %#x, want 2 and 0x2001", decoded.KeySequenceNumber, decoded.SAID)
+	}
+	if len(decoded.TEKParameters) != 1 || !bytes.Equal(decoded.TEKParameters[0].TEK, tek) {
+		t.Errorf("TEK parameters are %+v", decoded.TEKParameters)
+	}
+}
+
+func TestBuildManagementMessageBpkmMessage(t *testing.T) {
+	tek := []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}
+	digest := bytes.Repeat([]byte{0xaa}, 20)
+	rsp := &DOCSISBpkmRsp{}
+	rsp.Code = DocsisBpkmCodeKeyReply
+	rsp.Identifier = 4
+	rsp.Message = &BpkmKeyReply{
+		KeySequenceNumber: 3,
+		SAID:              0x2002,
+		TEKParameters:     []TEKParameters{{TEK: tek, KeyLifetime: 600, KeySequenceNumber: 3, CBCIV: make([]byte, 8)}},
+		HMACDigest:        digest,
+	}
+
+	data, err := BuildManagementMessage(testCMTSMAC, testModemMAC, DocsisManagementBpkmRsp, 1, rsp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded := &DOCSISBpkmRsp{}
+	decodeManagementMessage(t, data, decoded)
+	reply, ok := decoded.Message.(*BpkmKeyReply)
+	if !ok {
+		t.Fatalf("message is %T, want *BpkmKeyReply", decoded.Message)
+	}
+	if reply.KeySequenceNumber != 3 || reply.SAID != 0x2002 || !bytes.Equal(reply.HMACDigest, digest) {
+		t.Errorf("key reply is %+v", reply)
+	}
+	if len(reply.TEKParameters) != 1 || !bytes.Equal(reply.TEKParameters[0].TEK, tek) || reply.TEKParameters[0].KeyLifetime != 600 {
+		t.Errorf("TEK parameters are %+v", reply.TEKParameters)
+	}
+	if last := decoded.Attributes[len(decoded.Attributes)-1]; last.Type != BpkmAttrHMACDigest {
+		t.Errorf("last attribute is %d, want the HMAC digest", last.Type)
+	}
+
+	// without a message the typed fields are encoded
+	reject := &DOCSISBpkmRsp{}
+	reject.Code = DocsisBpkmCodeKeyReject
+	reject.SAID = 0x2002
+	reject.ErrorCode = 4
+	data, err = BuildManagementMessage(testCMTSMAC, testModemMAC, DocsisManagementBpkmRsp, 1, reject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded = &DOCSISBpkmRsp{}
+	decodeManagementMessage(t, data, decoded)
+	if decoded.SAID != 0x2002 || !decoded.ErrorCodePresent || decoded.ErrorCode != 4 {
+		t.Errorf("key reject is %+v", decoded.BpkmAttributes)
+	}
+
+	rsp.Code = DocsisBpkmCodeKeyReject
+	if _, err := BuildManagementMessage(testCMTSMAC, testModemMAC, DocsisManagementBpkmRsp, 1, rsp); err == nil {
+		t.Error("key reply message with key reject code serialized without error")
+	}
+}
+
+func TestDOCSISSerializeLength(t *testing.T) {
+	payload := gopacket.Payload{0x00, 0x01, 0x02}
+
+	buf := gopacket.NewSerializeBuffer()
+	docsis := &DOCSIS{FCType: 3, FCParm: 1, Length: 42}
+	if err := gopacket.SerializeLayers(buf, gopacket.SerializeOptions{}, docsis, payload); err != nil {
+		t.Fatal(err)
+	}
+	if length := int(buf.Bytes()[2])<<8 | int(buf.Bytes()[3]); length != 42 {
+		t.Errorf("length is %d without FixLengths, want 42", length)
+	}
+
+	if err := gopacket.SerializeLayers(buf, gopacket.SerializeOptions{FixLengths: true}, docsis, payload); err != nil {
+		t.Fatal(err)
+	}
+	if length := int(buf.Bytes()[2])<<8 | int(buf.Bytes()[3]); length != 3 || docsis.Length != 3 {
+		t.Errorf("length is %d with FixLengths, want 3", length)
+	}
+}
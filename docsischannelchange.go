@@ -0,0 +1,612 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// LayerTypeDOCSISUccReq type registration
+var LayerTypeDOCSISUccReq = gopacket.RegisterLayerType(1029, gopacket.LayerTypeMetadata{Name: "DOCSIS Management Upstream Channel Change Request", Decoder: gopacket.DecodeFunc(decodeDOCSISUccReq)})
+
+// LayerTypeDOCSISUccRsp type registration
+var LayerTypeDOCSISUccRsp = gopacket.RegisterLayerType(1030, gopacket.LayerTypeMetadata{Name: "DOCSIS Management Upstream Channel Change Response", Decoder: gopacket.DecodeFunc(decodeDOCSISUccRsp)})
+
+// LayerTypeDOCSISDccReq type registration
+var LayerTypeDOCSISDccReq = gopacket.RegisterLayerType(1031, gopacket.LayerTypeMetadata{Name: "DOCSIS Management Dynamic Channel Change Request", Decoder: gopacket.DecodeFunc(decodeDOCSISDccReq)})
+
+// LayerTypeDOCSISDccRsp type registration
+var LayerTypeDOCSISDccRsp = gopacket.RegisterLayerType(1032, gopacket.LayerTypeMetadata{Name: "DOCSIS Management Dynamic Channel Change Response", Decoder: gopacket.DecodeFunc(decodeDOCSISDccRsp)})
+
+// LayerTypeDOCSISDccAck type registration
+var LayerTypeDOCSISDccAck = gopacket.RegisterLayerType(1033, gopacket.LayerTypeMetadata{Name: "DOCSIS Management Dynamic Channel Change Acknowledge", Decoder: gopacket.DecodeFunc(decodeDOCSISDccAck)})
+
+// LayerTypeDOCSISDbcReq type registration
+var LayerTypeDOCSISDbcReq = gopacket.RegisterLayerType(1034, gopacket.LayerTypeMetadata{Name: "DOCSIS Management Dynamic Bonding Change Request", Decoder: gopacket.DecodeFunc(decodeDOCSISDbcReq)})
+
+// LayerTypeDOCSISDbcRsp type registration
+var LayerTypeDOCSISDbcRsp = gopacket.RegisterLayerType(1035, gopacket.LayerTypeMetadata{Name: "DOCSIS Management Dynamic Bonding Change Response", Decoder: gopacket.DecodeFunc(decodeDOCSISDbcRsp)})
+
+// LayerTypeDOCSISDbcAck type registration
+var LayerTypeDOCSISDbcAck = gopacket.RegisterLayerType(1036, gopacket.LayerTypeMetadata{Name: "DOCSIS Management Dynamic Bonding Change Acknowledge", Decoder: gopacket.DecodeFunc(decodeDOCSISDbcAck)})
+
+func init() {
+	RegisterManagementType(DocsisManagementUccReq, ManagementVersionAny, LayerTypeDOCSISUccReq, func() gopacket.DecodingLayer { return &DOCSISUccReq{} })
+	RegisterManagementType(DocsisManagementUccRsp, ManagementVersionAny, LayerTypeDOCSISUccRsp, func() gopacket.DecodingLayer { return &DOCSISUccRsp{} })
+	RegisterManagementType(DocsisManagementDccReq, ManagementVersionAny, LayerTypeDOCSISDccReq, func() gopacket.DecodingLayer { return &DOCSISDccReq{} })
+	RegisterManagementType(DocsisManagementDccRsp, ManagementVersionAny, LayerTypeDOCSISDccRsp, func() gopacket.DecodingLayer { return &DOCSISDccRsp{} })
+	RegisterManagementType(DocsisManagementDccAck, ManagementVersionAny, LayerTypeDOCSISDccAck, func() gopacket.DecodingLayer { return &DOCSISDccAck{} })
+	RegisterManagementType(DocsisManagementDbcReq, ManagementVersionAny, LayerTypeDOCSISDbcReq, func() gopacket.DecodingLayer { return &DOCSISDbcReq{} })
+	RegisterManagementType(DocsisManagementDbcRsp, ManagementVersionAny, LayerTypeDOCSISDbcRsp, func() gopacket.DecodingLayer { return &DOCSISDbcRsp{} })
+	RegisterManagementType(DocsisManagementDbcAck, ManagementVersionAny, LayerTypeDOCSISDbcAck, func() gopacket.DecodingLayer { return &DOCSISDbcAck{} })
+}
+
+// upstream channel actions of the transmit channel configuration
+const (
+	UpstreamChannelActionNone    = 0
+	UpstreamChannelActionAdd     = 1
+	UpstreamChannelActionChange  = 2
+	UpstreamChannelActionDelete  = 3
+	UpstreamChannelActionReplace = 4
+	UpstreamChannelActionReRange = 5
+)
+
+var dccReqSchema = &TLVSchema{
+	LengthSize: 1,
+	Nested: map[uint8]*TLVSchema{
+		// downstream parameters
+		2: nil,
+		// security association identifier substitution
+		6: nil,
+		// service flow substitutions
+		7: nil,
+	},
+}
+
+var dccRspSchema = &TLVSchema{
+	LengthSize: 1,
+	Nested: map[uint8]*TLVSchema{
+		// CM jump time
+		1: nil,
+	},
+}
+
+// UpstreamChannelAction is a decoded transmit channel configuration.
+type UpstreamChannelAction struct {
+	Action    uint8
+	ChannelID uint8
+	// NewChannelID is only set for replace actions
+	NewChannelID uint8
+}
+
+// DOCSISBaseUCC contains the fields shared by the upstream channel change messages.
+type DOCSISBaseUCC struct {
+	UpstreamChannelID uint8
+}
+
+func (docsis *DOCSISBaseUCC) parseUCC(data []byte) error {
+	if len(data) < 1 {
+		return fmt.Errorf("docsis ucc packet is too small for the header")
+	}
+
+	docsis.UpstreamChannelID = data[0]
+
+	return nil
+}
+
+// DOCSISBaseDCC contains the fields shared by the dynamic channel change messages.
+type DOCSISBaseDCC struct {
+	TransactionID uint16
+	// ConfirmationCode is only present in responses
+	ConfirmationCode        uint8
+	UpstreamChannelID       uint8
+	DownstreamFrequency     uint32
+	DownstreamChannelID     uint8
+	InitializationTechnique uint8
+	CMTSMAC                 net.HardwareAddr
+	TLVs                    TLVs
+}
+
+func (docsis *DOCSISBaseDCC) parseDCC(data []byte, hasConfirmationCode bool) error {
+	headerLen := 2
+	if hasConfirmationCode {
+		headerLen++
+	}
+	if len(data) < headerLen {
+		return fmt.Errorf("docsis dcc packet is too small for the header")
+	}
+
+	*docsis = DOCSISBaseDCC{}
+	docsis.TransactionID = binary.BigEndian.Uint16(data[0:2])
+	if hasConfirmationCode {
+		docsis.ConfirmationCode = data[2]
+	}
+
+	schema := dccReqSchema
+	if hasConfirmationCode {
+		schema = dccRspSchema
+	}
+
+	tlvs, err := DecodeTLVs(data[headerLen:], schema)
+	if err != nil {
+		return err
+	}
+
+	// the responses reuse the type numbers for other encodings
+	if !hasConfirmationCode {
+		for _, tlv := range tlvs {
+			switch tlv.Type {
+			case 1:
+				docsis.UpstreamChannelID, err = tlv.Uint8()
+			case 2:
+				for _, inner := range tlv.Children {
+					if inner.Type == 1 {
+						docsis.DownstreamFrequency, err = inner.Uint32()
+					} else if inner.Type == 5 {
+						docsis.DownstreamChannelID, err = inner.Uint8()
+					}
+					if err != nil {
+						break
+					}
+				}
+			case 3:
+				docsis.InitializationTechnique, err = tlv.Uint8()
+			case 8:
+				if len(tlv.Value) != 6 {
+					err = fmt.Errorf("tlv %d has length %d instead of 6", tlv.Type, len(tlv.Value))
+				}
+				docsis.CMTSMAC = net.HardwareAddr(tlv.Value)
+			}
+
+			if err != nil {
+				return fmt.Errorf("docsis dcc: %w", err)
+			}
+		}
+	}
+
+	docsis.TLVs = tlvs
+
+	return nil
+}
+
+// DOCSISBaseDBC contains the fields shared by the dynamic bonding change messages.
+type DOCSISBaseDBC struct {
+	TransactionID uint16
+	// ConfirmationCode is only present in responses
+	ConfirmationCode uint8
+	// the fragmentation fields are only present in requests
+	NumberOfFragments      uint8
+	FragmentSequenceNumber uint8
+	UpstreamChannelActions []UpstreamChannelAction
+	// DownstreamFrequencies are the receive channels of the receive channel configuration
+	DownstreamFrequencies []uint32
+	TLVs                  TLVs
+}
+
+func (docsis *DOCSISBaseDBC) parseDBC(data []byte, hasFragments bool, hasConfirmationCode bool) error {
+	headerLen := 2
+	if hasFragments {
+		headerLen += 2
+	}
+	if hasConfirmationCode {
+		headerLen++
+	}
+	if len(data) < headerLen {
+		return fmt.Errorf("docsis dbc packet is too small for the header")
+	}
+
+	*docsis = DOCSISBaseDBC{}
+	docsis.TransactionID = binary.BigEndian.Uint16(data[0:2])
+	if hasFragments {
+		docsis.NumberOfFragments = data[2]
+		docsis.FragmentSequenceNumber = data[3]
+	}
+	if hasConfirmationCode {
+		docsis.ConfirmationCode = data[2]
+	}
+
+	tlvs, err := DecodeTLVs(data[headerLen:], docsisConfigSchema)
+	if err != nil {
+		return err
+	}
+
+	actions, frequencies, err := parseChannelConfiguration(tlvs)
+	if err != nil {
+		return fmt.Errorf("docsis dbc: %w", err)
+	}
+
+	docsis.UpstreamChannelActions = actions
+	docsis.DownstreamFrequencies = frequencies
+	docsis.TLVs = tlvs
+
+	return nil
+}
+
+// parseChannelConfiguration decodes the transmit and receive channel configuration encodings.
+func parseChannelConfiguration(tlvs TLVs) ([]UpstreamChannelAction, []uint32, error) {
+	var actions []UpstreamChannelAction
+	var frequencies []uint32
+
+	var err error
+	for _, tcc := range tlvs.GetAll(46) {
+		action := UpstreamChannelAction{}
+		for _, inner := range tcc.Children {
+			if inner.Type == 2 {
+				action.Action, err = inner.Uint8()
+			} else if inner.Type == 3 {
+				action.ChannelID, err = inner.Uint8()
+			} else if inner.Type == 4 {
+				action.NewChannelID, err = inner.Uint8()
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		actions = append(actions, action)
+	}
+
+	for _, frequency := range tlvs.GetAll(49, 5, 4) {
+		var value uint32
+		if value, err = frequency.Uint32(); err != nil {
+			return nil, nil, err
+		}
+		frequencies = append(frequencies, value)
+	}
+
+	return actions, frequencies, nil
+}
+
+// DOCSISUccReq is a DOCSIS Management Upstream Channel Change Request.
+type DOCSISUccReq struct {
+	layers.BaseLayer
+	DOCSISBaseUCC
+}
+
+// LayerType returns LayerTypeDOCSISUccReq
+func (docsis *DOCSISUccReq) LayerType() gopacket.LayerType {
+	return LayerTypeDOCSISUccReq
+}
+
+// DecodeFromBytes decodes the given bytes into this layer.
+func (docsis *DOCSISUccReq) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
+	if err := docsis.parseUCC(data); err != nil {
+		return err
+	}
+
+	docsis.Contents = data[:]
+	docsis.Payload = data[:0]
+
+	return nil
+}
+
+// CanDecode returns the set of layer types that this DecodingLayer can decode.
+func (docsis *DOCSISUccReq) CanDecode() gopacket.LayerClass {
+	return LayerTypeDOCSISUccReq
+}
+
+// NextLayerType returns the layer type contained by this DecodingLayer.
+func (docsis *DOCSISUccReq) NextLayerType() gopacket.LayerType {
+	return gopacket.LayerTypePayload
+}
+
+func decodeDOCSISUccReq(data []byte, p gopacket.PacketBuilder) error {
+	docsis := &DOCSISUccReq{}
+	err := docsis.DecodeFromBytes(data, p)
+	if err != nil {
+		return err
+	}
+	p.AddLayer(docsis)
+
+	return p.NextDecoder(docsis.NextLayerType())
+}
+
+// DOCSISUccRsp is a DOCSIS Management Upstream Channel Change Response.
+type DOCSISUccRsp struct {
+	layers.BaseLayer
+	DOCSISBaseUCC
+}
+
+// LayerType returns LayerTypeDOCSISUccRsp
+func (docsis *DOCSISUccRsp) LayerType() gopacket.LayerType {
+	return LayerTypeDOCSISUccRsp
+}
+
+// DecodeFromBytes decodes the given bytes into this layer.
+func (docsis *DOCSISUccRsp) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
+	if err := docsis.parseUCC(data); err != nil {
+		return err
+	}
+
+	docsis.Contents = data[:]
+	docsis.Payload = data[:0]
+
+	return nil
+}
+
+// CanDecode returns the set of layer types that this DecodingLayer can decode.
+func (docsis *DOCSISUccRsp) CanDecode() gopacket.LayerClass {
+	return LayerTypeDOCSISUccRsp
+}
+
+// NextLayerType returns the layer type contained by this DecodingLayer.
+func (docsis *DOCSISUccRsp) NextLayerType() gopacket.LayerType {
+	return gopacket.LayerTypePayload
+}
+
+func decodeDOCSISUccRsp(data []byte, p gopacket.PacketBuilder) error {
+	docsis := &DOCSISUccRsp{}
+	err := docsis.DecodeFromBytes(data, p)
+	if err != nil {
+		return err
+	}
+	p.AddLayer(docsis)
+
+	return p.NextDecoder(docsis.NextLayerType())
+}
+
+// DOCSISDccReq is a DOCSIS Management Dynamic Channel Change Request.
+type DOCSISDccReq struct {
+	layers.BaseLayer
+	DOCSISBaseDCC
+}
+
+// LayerType returns LayerTypeDOCSISDccReq
+func (docsis *DOCSISDccReq) LayerType() gopacket.LayerType {
+	return LayerTypeDOCSISDccReq
+}
+
+// DecodeFromBytes decodes the given bytes into this layer.
+func (docsis *DOCSISDccReq) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
+	if err := docsis.parseDCC(data, false); err != nil {
+		return err
+	}
+
+	docsis.Contents = data[:]
+	docsis.Payload = data[:0]
+
+	return nil
+}
+
+// CanDecode returns the set of layer types that this DecodingLayer can decode.
+func (docsis *DOCSISDccReq) CanDecode() gopacket.LayerClass {
+	return LayerTypeDOCSISDccReq
+}
+
+// NextLayerType returns the layer type contained by this DecodingLayer.
+func (docsis *DOCSISDccReq) NextLayerType() gopacket.LayerType {
+	return gopacket.LayerTypePayload
+}
+
+func decodeDOCSISDccReq(data []byte, p gopacket.PacketBuilder) error {
+	docsis := &DOCSISDccReq{}
+	err := docsis.DecodeFromBytes(data, p)
+	if err != nil {
+		return err
+	}
+	p.AddLayer(docsis)
+
+	return p.NextDecoder(docsis.NextLayerType())
+}
+
+// DOCSISDccRsp is a DOCSIS Management Dynamic Channel Change Response.
+type DOCSISDccRsp struct {
+	layers.BaseLayer
+	DOCSISBaseDCC
+}
+
+// LayerType returns LayerTypeDOCSISDccRsp
+func (docsis *DOCSISDccRsp) LayerType() gopacket.LayerType {
+	return LayerTypeDOCSISDccRsp
+}
+
+// DecodeFromBytes decodes the given bytes into this layer.
+func (docsis *DOCSISDccRsp) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
+	if err := docsis.parseDCC(data, true); err != nil {
+		return err
+	}
+
+	docsis.Contents = data[:]
+	docsis.Payload = data[:0]
+
+	return nil
+}
+
+// CanDecode returns the set of layer types that this DecodingLayer can decode.
+func (docsis *DOCSISDccRsp) CanDecode() gopacket.LayerClass {
+	return LayerTypeDOCSISDccRsp
+}
+
+// NextLayerType returns the layer type contained by this DecodingLayer.
+func (docsis *DOCSISDccRsp) NextLayerType() gopacket.LayerType {
+	return gopacket.LayerTypePayload
+}
+
+func decodeDOCSISDccRsp(data []byte, p gopacket.PacketBuilder) error {
+	docsis := &DOCSISDccRsp{}
+	err := docsis.DecodeFromBytes(data, p)
+	if err != nil {
+		return err
+	}
+	p.AddLayer(docsis)
+
+	return p.NextDecoder(docsis.NextLayerType())
+}
+
+// DOCSISDccAck is a DOCSIS Management Dynamic Channel Change Acknowledge.
+type DOCSISDccAck struct {
+	layers.BaseLayer
+	DOCSISBaseDCC
+}
+
+// LayerType returns LayerTypeDOCSISDccAck
+func (docsis *DOCSISDccAck) LayerType() gopacket.LayerType {
+	return LayerTypeDOCSISDccAck
+}
+
+// DecodeFromBytes decodes the given bytes into this layer.
+func (docsis *DOCSISDccAck) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
+	if err := docsis.parseDCC(data, false); err != nil {
+		return err
+	}
+
+	docsis.Contents = data[:]
+	docsis.Payload = data[:0]
+
+	return nil
+}
+
+// CanDecode returns the set of layer types that this DecodingLayer can decode.
+func (docsis *DOCSISDccAck) CanDecode() gopacket.LayerClass {
+	return LayerTypeDOCSISDccAck
+}
+
+// NextLayerType returns the layer type contained by this DecodingLayer.
+func (docsis *DOCSISDccAck) NextLayerType() gopacket.LayerType {
+	return gopacket.LayerTypePayload
+}
+
+func decodeDOCSISDccAck(data []byte, p gopacket.PacketBuilder) error {
+	docsis := &DOCSISDccAck{}
+	err := docsis.DecodeFromBytes(data, p)
+	if err != nil {
+		return err
+	}
+	p.AddLayer(docsis)
+
+	return p.NextDecoder(docsis.NextLayerType())
+}
+
+// DOCSISDbcReq is a DOCSIS Management Dynamic Bonding Change Request.
+type DOCSISDbcReq struct {
+	layers.BaseLayer
+	DOCSISBaseDBC
+}
+
+// LayerType returns LayerTypeDOCSISDbcReq
+func (docsis *DOCSISDbcReq) LayerType() gopacket.LayerType {
+	return LayerTypeDOCSISDbcReq
+}
+
+// DecodeFromBytes decodes the given bytes into this layer.
+func (docsis *DOCSISDbcReq) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
+	if err := docsis.parseDBC(data, true, false); err != nil {
+		return err
+	}
+
+	docsis.Contents = data[:]
+	docsis.Payload = data[:0]
+
+	return nil
+}
+
+// CanDecode returns the set of layer types that this DecodingLayer can decode.
+func (docsis *DOCSISDbcReq) CanDecode() gopacket.LayerClass {
+	return LayerTypeDOCSISDbcReq
+}
+
+// NextLayerType returns the layer type contained by this DecodingLayer.
+func (docsis *DOCSISDbcReq) NextLayerType() gopacket.LayerType {
+	return gopacket.LayerTypePayload
+}
+
+func decodeDOCSISDbcReq(data []byte, p gopacket.PacketBuilder) error {
+	docsis := &DOCSISDbcReq{}
+	err := docsis.DecodeFromBytes(data, p)
+	if err != nil {
+		return err
+	}
+	p.AddLayer(docsis)
+
+	return p.NextDecoder(docsis.NextLayerType())
+}
+
+// DOCSISDbcRsp is a DOCSIS Management Dynamic Bonding Change Response.
+type DOCSISDbcRsp struct {
+	layers.BaseLayer
+	DOCSISBaseDBC
+}
+
+// LayerType returns LayerTypeDOCSISDbcRsp
+func (docsis *DOCSISDbcRsp) LayerType() gopacket.LayerType {
+	return LayerTypeDOCSISDbcRsp
+}
+
+// DecodeFromBytes decodes the given bytes into this layer.
+func (docsis *DOCSISDbcRsp) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
+	if err := docsis.parseDBC(data, false, true); err != nil {
+		return err
+	}
+
+	docsis.Contents = data[:]
+	docsis.Payload = data[:0]
+
+	return nil
+}
+
+// CanDecode returns the set of layer types that this DecodingLayer can decode.
+func (docsis *DOCSISDbcRsp) CanDecode() gopacket.LayerClass {
+	return LayerTypeDOCSISDbcRsp
+}
+
+// NextLayerType returns the layer type contained by this DecodingLayer.
+func (docsis *DOCSISDbcRsp) NextLayerType() gopacket.LayerType {
+	return gopacket.LayerTypePayload
+}
+
+func decodeDOCSISDbcRsp(data []byte, p gopacket.PacketBuilder) error {
+	docsis := &DOCSISDbcRsp{}
+	err := docsis.DecodeFromBytes(data, p)
+	if err != nil {
+		return err
+	}
+	p.AddLayer(docsis)
+
+	return p.NextDecoder(docsis.NextLayerType())
+}
+
+// DOCSISDbcAck is a DOCSIS Management Dynamic Bonding Change Acknowledge.
+type DOCSISDbcAck struct {
+	layers.BaseLayer
+	DOCSISBaseDBC
+}
+
+// LayerType returns LayerTypeDOCSISDbcAck
+func (docsis *DOCSISDbcAck) LayerType() gopacket.LayerType {
+	return LayerTypeDOCSISDbcAck
+}
+
+// DecodeFromBytes decodes the given bytes into this layer.
+func (docsis *DOCSISDbcAck) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
+	if err := docsis.parseDBC(data, false, false); err != nil {
+		return err
+	}
+
+	docsis.Contents = data[:]
+	docsis.Payload = data[:0]
+
+	return nil
+}
+
+// CanDecode returns the set of layer types that this DecodingLayer can decode.
+func (docsis *DOCSISDbcAck) CanDecode() gopacket.LayerClass {
+	return LayerTypeDOCSISDbcAck
+}
+
+// NextLayerType returns the layer type contained by this DecodingLayer.
+func (docsis *DOCSISDbcAck) NextLayerType() gopacket.LayerType {
+	return gopacket.LayerTypePayload
+}
+
+func decodeDOCSISDbcAck(data []byte, p gopacket.PacketBuilder) error {
+	docsis := &DOCSISDbcAck{}
+	err := docsis.DecodeFromBytes(data, p)
+	if err != nil {
+		return err
+	}
+	p.AddLayer(docsis)
+
+	return p.NextDecoder(docsis.NextLayerType())
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDOCSISDccReqDecode(t *testing.T) {
+	dcc := &DOCSISDccReq{}
+	if err := dcc.DecodeFromBytes([]byte{0x00, 0x01, 0x01, 0x01, 0x05, 0x03, 0x01, 0x00}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if dcc.TransactionID != 1 || dcc.UpstreamChannelID != 5 || dcc.InitializationTechnique != 0 {
+		t.Errorf("decoded %+v, want transaction 1 on upstream channel 5", dcc.DOCSISBaseDCC)
+	}
+
+	data := []byte{
+		0x00, 0x02,
+		0x02, 0x09, 0x01, 0x04, 0x23, 0xc3, 0x46, 0x00, 0x05, 0x01, 0x07, // 600 MHz, downstream channel 7
+		0x07, 0x03, 0x01, 0x01, 0x02, // service flow substitution
+		0x08, 0x06, 0x00, 0x01, 0x02, 0x03, 0x04, 0x05, // CMTS MAC
+	}
+	if err := dcc.DecodeFromBytes(data, nil); err != nil {
+		t.Fatal(err)
+	}
+	if dcc.DownstreamFrequency != 600000000 || dcc.DownstreamChannelID != 7 {
+		t.Errorf("downstream is %d Hz on channel %d, want 600000000 Hz on channel 7", dcc.DownstreamFrequency, dcc.DownstreamChannelID)
+	}
+	if !bytes.Equal(dcc.CMTSMAC, []byte{0x00, 0x01, 0x02, 0x03, 0x04, 0x05}) {
+		t.Errorf("CMTS MAC is %v", dcc.CMTSMAC)
+	}
+	if tlv, ok := dcc.TLVs.Get(7); !ok || len(tlv.Children) != 1 {
+		t.Errorf("service flow substitution wasn't decoded as nested tlv: %v", tlv)
+	}
+}
+
+func TestDOCSISDccRspDecode(t *testing.T) {
+	dcc := &DOCSISDccRsp{}
+	data := []byte{
+		0x00, 0x01, 0x00,
+		0x01, 0x06, 0x01, 0x04, 0x00, 0x00, 0x27, 0x10, // CM jump time length
+	}
+	if err := dcc.DecodeFromBytes(data, nil); err != nil {
+		t.Fatal(err)
+	}
+	if dcc.TransactionID != 1 || dcc.ConfirmationCode != 0 {
+		t.Errorf("decoded %+v, want transaction 1 with code 0", dcc.DOCSISBaseDCC)
+	}
+	if dcc.UpstreamChannelID != 0 {
+		t.Errorf("CM jump time was taken as upstream channel %d", dcc.UpstreamChannelID)
+	}
+	jump, ok := dcc.TLVs.Get(1)
+	if !ok || len(jump.Children) != 1 {
+		t.Fatalf("CM jump time wasn't decoded as nested tlv: %v", jump)
+	}
+	if length, err := jump.Children[0].Uint32(); err != nil || length != 10000 {
+		t.Errorf("jump length is %d (%v), want 10000", length, err)
+	}
+}
@@ -0,0 +1,346 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// LayerTypeDOCSISCMStatus type registration
+var LayerTypeDOCSISCMStatus = gopacket.RegisterLayerType(1039, gopacket.LayerTypeMetadata{Name: "DOCSIS Management CM Status Report", Decoder: gopacket.DecodeFunc(decodeDOCSISCMStatus)})
+
+// LayerTypeDOCSISCMCtrlReq type registration
+var LayerTypeDOCSISCMCtrlReq = gopacket.RegisterLayerType(1040, gopacket.LayerTypeMetadata{Name: "DOCSIS Management CM Control Request", Decoder: gopacket.DecodeFunc(decodeDOCSISCMCtrlReq)})
+
+// LayerTypeDOCSISCMCtrlRsp type registration
+var LayerTypeDOCSISCMCtrlRsp = gopacket.RegisterLayerType(1041, gopacket.LayerTypeMetadata{Name: "DOCSIS Management CM Control Response", Decoder: gopacket.DecodeFunc(decodeDOCSISCMCtrlRsp)})
+
+func init() {
+	RegisterManagementType(DocsisManagementCMStatus, ManagementVersionAny, LayerTypeDOCSISCMStatus, func() gopacket.DecodingLayer { return &DOCSISCMStatus{} })
+	RegisterManagementType(DocsisManagementCMCtrlReq, ManagementVersionAny, LayerTypeDOCSISCMCtrlReq, func() gopacket.DecodingLayer { return &DOCSISCMCtrlReq{} })
+	RegisterManagementType(DocsisManagementCMCtrlRsp, ManagementVersionAny, LayerTypeDOCSISCMCtrlRsp, func() gopacket.DecodingLayer { return &DOCSISCMCtrlRsp{} })
+}
+
+// CMStatusEventType is the event reported by a CM-STATUS message.
+type CMStatusEventType uint8
+
+// CM-STATUS event types
+const (
+	CMStatusSecondaryChannelMDDTimeout   CMStatusEventType = 1
+	CMStatusQAMFECLockFailure            CMStatusEventType = 2
+	CMStatusSequenceOutOfRange           CMStatusEventType = 3
+	CMStatusSecondaryChannelMDDRecovery  CMStatusEventType = 4
+	CMStatusQAMFECLockRecovery           CMStatusEventType = 5
+	CMStatusT4Timeout                    CMStatusEventType = 6
+	CMStatusT3RetriesExceeded            CMStatusEventType = 7
+	CMStatusSuccessfulRangingAfterT3     CMStatusEventType = 8
+	CMStatusOperatingOnBatteryBackup     CMStatusEventType = 9
+	CMStatusReturnedToACPower            CMStatusEventType = 10
+	CMStatusMACRemoval                   CMStatusEventType = 11
+	CMStatusDownstreamOFDMProfileFailure CMStatusEventType = 16
+	CMStatusPrimaryDownstreamChange      CMStatusEventType = 17
+	CMStatusDPDMismatch                  CMStatusEventType = 18
+	CMStatusNCPProfileFailure            CMStatusEventType = 20
+	CMStatusPLCFailure                   CMStatusEventType = 21
+	CMStatusNCPProfileRecovery           CMStatusEventType = 22
+	CMStatusPLCRecovery                  CMStatusEventType = 23
+	CMStatusOFDMProfileRecovery          CMStatusEventType = 24
+	CMStatusOFDMAProfileFailure          CMStatusEventType = 25
+	CMStatusMAPStorageOverflow           CMStatusEventType = 26
+	CMStatusMAPStorageAlmostFull         CMStatusEventType = 27
+)
+
+var cmStatusEventTypeNames = map[CMStatusEventType]string{
+	CMStatusSecondaryChannelMDDTimeout:   "secondary channel MDD timeout",
+	CMStatusQAMFECLockFailure:            "QAM/FEC lock failure",
+	CMStatusSequenceOutOfRange:           "sequence out-of-range",
+	CMStatusSecondaryChannelMDDRecovery:  "secondary channel MDD recovery",
+	CMStatusQAMFECLockRecovery:           "QAM/FEC lock recovery",
+	CMStatusT4Timeout:                    "T4 timeout",
+	CMStatusT3RetriesExceeded:            "T3 retries exceeded",
+	CMStatusSuccessfulRangingAfterT3:     "successful ranging after T3 retries exceeded",
+	CMStatusOperatingOnBatteryBackup:     "operating on battery backup",
+	CMStatusReturnedToACPower:            "returned to A/C power",
+	CMStatusMACRemoval:                   "MAC removal",
+	CMStatusDownstreamOFDMProfileFailure: "downstream OFDM profile failure",
+	CMStatusPrimaryDownstreamChange:      "primary downstream change",
+	CMStatusDPDMismatch:                  "DPD mismatch",
+	CMStatusNCPProfileFailure:            "NCP profile failure",
+	CMStatusPLCFailure:                   "PLC failure",
+	CMStatusNCPProfileRecovery:           "NCP profile recovery",
+	CMStatusPLCRecovery:                  "PLC recovery",
+	CMStatusOFDMProfileRecovery:          "OFDM profile recovery",
+	CMStatusOFDMAProfileFailure:          "OFDMA profile failure",
+	CMStatusMAPStorageOverflow:           "MAP storage overflow",
+	CMStatusMAPStorageAlmostFull:         "MAP storage almost full",
+}
+
+func (eventType CMStatusEventType) String() string {
+	if name, ok := cmStatusEventTypeNames[eventType]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown event %d", uint8(eventType))
+}
+
+var cmStatusSchema = &TLVSchema{
+	LengthSize: 1,
+	Nested: map[uint8]*TLVSchema{
+		// status event
+		1: nil,
+	},
+}
+
+// DOCSISCMStatus is a DOCSIS Management CM Status Report.
+type DOCSISCMStatus struct {
+	layers.BaseLayer
+	TransactionID        uint16
+	EventType            CMStatusEventType
+	DownstreamChannelIDs []uint8
+	UpstreamChannelIDs   []uint8
+	DSID                 uint32
+	MAC                  net.HardwareAddr
+	TLVs                 TLVs
+}
+
+// LayerType returns LayerTypeDOCSISCMStatus
+func (docsis *DOCSISCMStatus) LayerType() gopacket.LayerType {
+	return LayerTypeDOCSISCMStatus
+}
+
+// DecodeFromBytes decodes the given bytes into this layer.
+func (docsis *DOCSISCMStatus) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
+	if len(data) < 3 {
+		return fmt.Errorf("docsis cm status packet is too small for the header")
+	}
+
+	*docsis = DOCSISCMStatus{}
+	docsis.TransactionID = binary.BigEndian.Uint16(data[0:2])
+	docsis.EventType = CMStatusEventType(data[2])
+
+	tlvs, err := DecodeTLVs(data[3:], cmStatusSchema)
+	if err != nil {
+		return err
+	}
+
+	for _, tlv := range tlvs {
+		if tlv.Type != 1 {
+			continue
+		}
+		if tlv.Err != nil {
+			return fmt.Errorf("docsis cm status: %w", tlv.Err)
+		}
+
+		for _, inner := range tlv.Children {
+			switch inner.Type {
+			case 1:
+				docsis.DownstreamChannelIDs = append(docsis.DownstreamChannelIDs, inner.Value...)
+			case 2:
+				docsis.UpstreamChannelIDs = append(docsis.UpstreamChannelIDs, inner.Value...)
+			case 3:
+				if len(inner.Value) != 3 {
+					err = fmt.Errorf("tlv %d has length %d instead of 3", inner.Type, len(inner.Value))
+				} else {
+					docsis.DSID = uint32(inner.Value[0])<<16 | uint32(binary.BigEndian.Uint16(inner.Value[1:3]))
+				}
+			case 4:
+				if len(inner.Value) != 6 {
+					err = fmt.Errorf("tlv %d has length %d instead of 6", inner.Type, len(inner.Value))
+				} else {
+					docsis.MAC = net.HardwareAddr(inner.Value)
+				}
+			}
+
+			if err != nil {
+				return fmt.Errorf("docsis cm status: %w", err)
+			}
+		}
+	}
+
+	docsis.TLVs = tlvs
+	docsis.Contents = data[:]
+	docsis.Payload = data[:0]
+
+	return nil
+}
+
+// CanDecode returns the set of layer types that this DecodingLayer can decode.
+func (docsis *DOCSISCMStatus) CanDecode() gopacket.LayerClass {
+	return LayerTypeDOCSISCMStatus
+}
+
+// NextLayerType returns the layer type contained by this DecodingLayer.
+func (docsis *DOCSISCMStatus) NextLayerType() gopacket.LayerType {
+	return gopacket.LayerTypePayload
+}
+
+func decodeDOCSISCMStatus(data []byte, p gopacket.PacketBuilder) error {
+	docsis := &DOCSISCMStatus{}
+	err := docsis.DecodeFromBytes(data, p)
+	if err != nil {
+		return err
+	}
+	p.AddLayer(docsis)
+
+	return p.NextDecoder(docsis.NextLayerType())
+}
+
+var cmCtrlSchema = &TLVSchema{
+	LengthSize: 1,
+	Nested: map[uint8]*TLVSchema{
+		// upstream channel RF mute
+		1: nil,
+		// override downstream and upstream events
+		4: nil,
+		5: nil,
+	},
+}
+
+// DOCSISBaseCMCtrl are the fields shared by CM-CTRL-REQ and CM-CTRL-RSP.
+// The response repeats the encodings of the request it confirms.
+type DOCSISBaseCMCtrl struct {
+	TransactionID uint16
+	// RFMuteChannelID and RFMuteTimeout (in ms) are set if the upstream channel RF mute is present
+	RFMute            bool
+	RFMuteChannelID   uint8
+	RFMuteTimeout     uint32
+	Reinitialize      bool
+	DisableForwarding bool
+	TLVs              TLVs
+}
+
+func (docsis *DOCSISBaseCMCtrl) parseCMCtrl(data []byte) error {
+	if len(data) < 2 {
+		return fmt.Errorf("docsis cm ctrl packet is too small for the header")
+	}
+
+	*docsis = DOCSISBaseCMCtrl{}
+	docsis.TransactionID = binary.BigEndian.Uint16(data[0:2])
+
+	tlvs, err := DecodeTLVs(data[2:], cmCtrlSchema)
+	if err != nil {
+		return err
+	}
+
+	for _, tlv := range tlvs {
+		switch tlv.Type {
+		case 1:
+			docsis.RFMute = true
+			err = tlv.Err
+			for _, inner := range tlv.Children {
+				if inner.Type == 1 {
+					docsis.RFMuteChannelID, err = inner.Uint8()
+				} else if inner.Type == 2 {
+					docsis.RFMuteTimeout, err = inner.Uint32()
+				}
+				if err != nil {
+					break
+				}
+			}
+		case 2:
+			docsis.Reinitialize = true
+		case 3:
+			var disable uint8
+			disable, err = tlv.Uint8()
+			docsis.DisableForwarding = disable == 1
+		}
+
+		if err != nil {
+			return fmt.Errorf("docsis cm ctrl: %w", err)
+		}
+	}
+
+	docsis.TLVs = tlvs
+
+	return nil
+}
+
+// DOCSISCMCtrlReq is a DOCSIS Management CM Control Request.
+type DOCSISCMCtrlReq struct {
+	layers.BaseLayer
+	DOCSISBaseCMCtrl
+}
+
+// LayerType returns LayerTypeDOCSISCMCtrlReq
+func (docsis *DOCSISCMCtrlReq) LayerType() gopacket.LayerType {
+	return LayerTypeDOCSISCMCtrlReq
+}
+
+// DecodeFromBytes decodes the given bytes into this layer.
+func (docsis *DOCSISCMCtrlReq) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
+	if err := docsis.parseCMCtrl(data); err != nil {
+		return err
+	}
+
+	docsis.Contents = data[:]
+	docsis.Payload = data[:0]
+
+	return nil
+}
+
+// CanDecode returns the set of layer types that this DecodingLayer can decode.
+func (docsis *DOCSISCMCtrlReq) CanDecode() gopacket.LayerClass {
+	return LayerTypeDOCSISCMCtrlReq
+}
+
+// NextLayerType returns the layer type contained by this DecodingLayer.
+func (docsis *DOCSISCMCtrlReq) NextLayerType() gopacket.LayerType {
+	return gopacket.LayerTypePayload
+}
+
+func decodeDOCSISCMCtrlReq(data []byte, p gopacket.PacketBuilder) error {
+	docsis := &DOCSISCMCtrlReq{}
+	err := docsis.DecodeFromBytes(data, p)
+	if err != nil {
+		return err
+	}
+	p.AddLayer(docsis)
+
+	return p.NextDecoder(docsis.NextLayerType())
+}
+
+// DOCSISCMCtrlRsp is a DOCSIS Management CM Control Response.
+type DOCSISCMCtrlRsp struct {
+	layers.BaseLayer
+	DOCSISBaseCMCtrl
+}
+
+// LayerType returns LayerTypeDOCSISCMCtrlRsp
+func (docsis *DOCSISCMCtrlRsp) LayerType() gopacket.LayerType {
+	return LayerTypeDOCSISCMCtrlRsp
+}
+
+// DecodeFromBytes decodes the given bytes into this layer.
+func (docsis *DOCSISCMCtrlRsp) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
+	if err := docsis.parseCMCtrl(data); err != nil {
+		return err
+	}
+
+	docsis.Contents = data[:]
+	docsis.Payload = data[:0]
+
+	return nil
+}
+
+// CanDecode returns the set of layer types that this DecodingLayer can decode.
+func (docsis *DOCSISCMCtrlRsp) CanDecode() gopacket.LayerClass {
+	return LayerTypeDOCSISCMCtrlRsp
+}
+
+// NextLayerType returns the layer type contained by this DecodingLayer.
+func (docsis *DOCSISCMCtrlRsp) NextLayerType() gopacket.LayerType {
+	return gopacket.LayerTypePayload
+}
+
+func decodeDOCSISCMCtrlRsp(data []byte, p gopacket.PacketBuilder) error {
+	docsis := &DOCSISCMCtrlRsp{}
+	err := docsis.DecodeFromBytes(data, p)
+	if err != nil {
+		return err
+	}
+	p.AddLayer(docsis)
+
+	return p.NextDecoder(docsis.NextLayerType())
+}
@@ -0,0 +1,25 @@
+package main
+
+import "testing"
+
+func TestDOCSISCMStatusDecode(t *testing.T) {
+	status := &DOCSISCMStatus{}
+	data := []byte{
+		0x00, 0x07, 0x02, // transaction id, QAM/FEC lock failure
+		0x01, 0x07, 0x01, 0x02, 0x03, 0x04, 0x02, 0x01, 0x05, // downstream channels 3 and 4, upstream channel 5
+	}
+	if err := status.DecodeFromBytes(data, nil); err != nil {
+		t.Fatal(err)
+	}
+	if status.TransactionID != 7 || status.EventType != CMStatusQAMFECLockFailure {
+		t.Errorf("decoded transaction %d event %v", status.TransactionID, status.EventType)
+	}
+	if len(status.DownstreamChannelIDs) != 2 || status.DownstreamChannelIDs[1] != 4 || len(status.UpstreamChannelIDs) != 1 {
+		t.Errorf("downstream channels %v, upstream channels %v", status.DownstreamChannelIDs, status.UpstreamChannelIDs)
+	}
+
+	// the downstream channel TLV claims 2 bytes but only 1 follows
+	if err := status.DecodeFromBytes([]byte{0x00, 0x07, 0x02, 0x01, 0x03, 0x01, 0x02, 0x03}, nil); err == nil {
+		t.Error("corrupt status event decoded without error")
+	}
+}
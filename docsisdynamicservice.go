@@ -0,0 +1,445 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// LayerTypeDOCSISDsaReq type registration
+var LayerTypeDOCSISDsaReq = gopacket.RegisterLayerType(1021, gopacket.LayerTypeMetadata{Name: "DOCSIS Management Dynamic Service Addition Request", Decoder: gopacket.DecodeFunc(decodeDOCSISDsaReq)})
+
+// LayerTypeDOCSISDsaRsp type registration
+var LayerTypeDOCSISDsaRsp = gopacket.RegisterLayerType(1022, gopacket.LayerTypeMetadata{Name: "DOCSIS Management Dynamic Service Addition Response", Decoder: gopacket.DecodeFunc(decodeDOCSISDsaRsp)})
+
+// LayerTypeDOCSISDsaAck type registration
+var LayerTypeDOCSISDsaAck = gopacket.RegisterLayerType(1023, gopacket.LayerTypeMetadata{Name: "DOCSIS Management Dynamic Service Addition Acknowledge", Decoder: gopacket.DecodeFunc(decodeDOCSISDsaAck)})
+
+// LayerTypeDOCSISDscReq type registration
+var LayerTypeDOCSISDscReq = gopacket.RegisterLayerType(1024, gopacket.LayerTypeMetadata{Name: "DOCSIS Management Dynamic Service Change Request", Decoder: gopacket.DecodeFunc(decodeDOCSISDscReq)})
+
+// LayerTypeDOCSISDscRsp type registration
+var LayerTypeDOCSISDscRsp = gopacket.RegisterLayerType(1025, gopacket.LayerTypeMetadata{Name: "DOCSIS Management Dynamic Service Change Response", Decoder: gopacket.DecodeFunc(decodeDOCSISDscRsp)})
+
+// LayerTypeDOCSISDscAck type registration
+var LayerTypeDOCSISDscAck = gopacket.RegisterLayerType(1026, gopacket.LayerTypeMetadata{Name: "DOCSIS Management Dynamic Service Change Acknowledge", Decoder: gopacket.DecodeFunc(decodeDOCSISDscAck)})
+
+// LayerTypeDOCSISDsdReq type registration
+var LayerTypeDOCSISDsdReq = gopacket.RegisterLayerType(1027, gopacket.LayerTypeMetadata{Name: "DOCSIS Management Dynamic Service Deletion Request", Decoder: gopacket.DecodeFunc(decodeDOCSISDsdReq)})
+
+// LayerTypeDOCSISDsdRsp type registration
+var LayerTypeDOCSISDsdRsp = gopacket.RegisterLayerType(1028, gopacket.LayerTypeMetadata{Name: "DOCSIS Management Dynamic Service Deletion Response", Decoder: gopacket.DecodeFunc(decodeDOCSISDsdRsp)})
+
+func init() {
+	RegisterManagementType(DocsisManagementDsaReq, ManagementVersionAny, LayerTypeDOCSISDsaReq, func() gopacket.DecodingLayer { return &DOCSISDsaReq{} })
+	RegisterManagementType(DocsisManagementDsaRsp, ManagementVersionAny, LayerTypeDOCSISDsaRsp, func() gopacket.DecodingLayer { return &DOCSISDsaRsp{} })
+	RegisterManagementType(DocsisManagementDsaAck, ManagementVersionAny, LayerTypeDOCSISDsaAck, func() gopacket.DecodingLayer { return &DOCSISDsaAck{} })
+	RegisterManagementType(DocsisManagementDscReq, ManagementVersionAny, LayerTypeDOCSISDscReq, func() gopacket.DecodingLayer { return &DOCSISDscReq{} })
+	RegisterManagementType(DocsisManagementDscRsp, ManagementVersionAny, LayerTypeDOCSISDscRsp, func() gopacket.DecodingLayer { return &DOCSISDscRsp{} })
+	RegisterManagementType(DocsisManagementDscAck, ManagementVersionAny, LayerTypeDOCSISDscAck, func() gopacket.DecodingLayer { return &DOCSISDscAck{} })
+	RegisterManagementType(DocsisManagementDsdReq, ManagementVersionAny, LayerTypeDOCSISDsdReq, func() gopacket.DecodingLayer { return &DOCSISDsdReq{} })
+	RegisterManagementType(DocsisManagementDsdRsp, ManagementVersionAny, LayerTypeDOCSISDsdRsp, func() gopacket.DecodingLayer { return &DOCSISDsdRsp{} })
+}
+
+// DOCSISBaseDynamicService contains the fields shared by the dynamic service messages.
+type DOCSISBaseDynamicService struct {
+	TransactionID uint16
+	// ConfirmationCode is only present in responses and acknowledgements
+	ConfirmationCode uint8
+	// SFID is the service flow to delete, it's only present in DSD-REQ
+	SFID         uint32
+	ServiceFlows []ServiceFlow
+	TLVs         TLVs
+}
+
+func (docsis *DOCSISBaseDynamicService) parseDynamicService(data []byte, hasConfirmationCode bool, reserved int, hasSFID bool) error {
+	headerLen := 2 + reserved
+	if hasConfirmationCode {
+		headerLen++
+	}
+	if hasSFID {
+		headerLen += 4
+	}
+	if len(data) < headerLen {
+		return fmt.Errorf("docsis dynamic service packet is too small for the header")
+	}
+
+	docsis.TransactionID = binary.BigEndian.Uint16(data[0:2])
+	docsis.ConfirmationCode = 0
+	if hasConfirmationCode {
+		docsis.ConfirmationCode = data[2]
+	}
+	docsis.SFID = 0
+	if hasSFID {
+		docsis.SFID = binary.BigEndian.Uint32(data[headerLen-4 : headerLen])
+	}
+
+	tlvs, err := DecodeTLVs(data[headerLen:], docsisConfigSchema)
+	if err != nil {
+		return err
+	}
+
+	serviceFlows, err := parseServiceFlows(tlvs)
+	if err != nil {
+		return err
+	}
+
+	docsis.ServiceFlows = serviceFlows
+	docsis.TLVs = tlvs
+
+	return nil
+}
+
+// DOCSISDsaReq is a DOCSIS Management Dynamic Service Addition Request.
+type DOCSISDsaReq struct {
+	layers.BaseLayer
+	DOCSISBaseDynamicService
+}
+
+// LayerType returns LayerTypeDOCSISDsaReq
+func (docsis *DOCSISDsaReq) LayerType() gopacket.LayerType {
+	return LayerTypeDOCSISDsaReq
+}
+
+// DecodeFromBytes decodes the given bytes into this layer.
+func (docsis *DOCSISDsaReq) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
+	if err := docsis.parseDynamicService(data, false, 0, false); err != nil {
+		return err
+	}
+
+	docsis.Contents = data[:]
+	docsis.Payload = data[:0]
+
+	return nil
+}
+
+// CanDecode returns the set of layer types that this DecodingLayer can decode.
+func (docsis *DOCSISDsaReq) CanDecode() gopacket.LayerClass {
+	return LayerTypeDOCSISDsaReq
+}
+
+// NextLayerType returns the layer type contained by this DecodingLayer.
+func (docsis *DOCSISDsaReq) NextLayerType() gopacket.LayerType {
+	return gopacket.LayerTypePayload
+}
+
+func decodeDOCSISDsaReq(data []byte, p gopacket.PacketBuilder) error {
+	docsis := &DOCSISDsaReq{}
+	err := docsis.DecodeFromBytes(data, p)
+	if err != nil {
+		return err
+	}
+	p.AddLayer(docsis)
+
+	return p.NextDecoder(docsis.NextLayerType())
+}
+
+// DOCSISDsaRsp is a DOCSIS Management Dynamic Service Addition Response.
+type DOCSISDsaRsp struct {
+	layers.BaseLayer
+	DOCSISBaseDynamicService
+}
+
+// LayerType returns LayerTypeDOCSISDsaRsp
+func (docsis *DOCSISDsaRsp) LayerType() gopacket.LayerType {
+	return LayerTypeDOCSISDsaRsp
+}
+
+// DecodeFromBytes decodes the given bytes into this layer.
+func (docsis *DOCSISDsaRsp) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
+	if err := docsis.parseDynamicService(data, true, 0, false); err != nil {
+		return err
+	}
+
+	docsis.Contents = data[:]
+	docsis.Payload = data[:0]
+
+	return nil
+}
+
+// CanDecode returns the set of layer types that this DecodingLayer can decode.
+func (docsis *DOCSISDsaRsp) CanDecode() gopacket.LayerClass {
+	return LayerTypeDOCSISDsaRsp
+}
+
+// NextLayerType returns the layer type contained by this DecodingLayer.
+func (docsis *DOCSISDsaRsp) NextLayerType() gopacket.LayerType {
+	return gopacket.LayerTypePayload
+}
+
+func decodeDOCSISDsaRsp(data []byte, p gopacket.PacketBuilder) error {
+	docsis := &DOCSISDsaRsp{}
+	err := docsis.DecodeFromBytes(data, p)
+	if err != nil {
+		return err
+	}
+	p.AddLayer(docsis)
+
+	return p.NextDecoder(docsis.NextLayerType())
+}
+
+// DOCSISDsaAck is a DOCSIS Management Dynamic Service Addition Acknowledge.
+type DOCSISDsaAck struct {
+	layers.BaseLayer
+	DOCSISBaseDynamicService
+}
+
+// LayerType returns LayerTypeDOCSISDsaAck
+func (docsis *DOCSISDsaAck) LayerType() gopacket.LayerType {
+	return LayerTypeDOCSISDsaAck
+}
+
+// DecodeFromBytes decodes the given bytes into this layer.
+func (docsis *DOCSISDsaAck) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
+	if err := docsis.parseDynamicService(data, true, 0, false); err != nil {
+		return err
+	}
+
+	docsis.Contents = data[:]
+	docsis.Payload = data[:0]
+
+	return nil
+}
+
+// CanDecode returns the set of layer types that this DecodingLayer can decode.
+func (docsis *DOCSISDsaAck) CanDecode() gopacket.LayerClass {
+	return LayerTypeDOCSISDsaAck
+}
+
+// NextLayerType returns the layer type contained by this DecodingLayer.
+func (docsis *DOCSISDsaAck) NextLayerType() gopacket.LayerType {
+	return gopacket.LayerTypePayload
+}
+
+func decodeDOCSISDsaAck(data []byte, p gopacket.PacketBuilder) error {
+	docsis := &DOCSISDsaAck{}
+	err := docsis.DecodeFromBytes(data, p)
+	if err != nil {
+		return err
+	}
+	p.AddLayer(docsis)
+
+	return p.NextDecoder(docsis.NextLayerType())
+}
+
+// DOCSISDscReq is a DOCSIS Management Dynamic Service Change Request.
+type DOCSISDscReq struct {
+	layers.BaseLayer
+	DOCSISBaseDynamicService
+}
+
+// LayerType returns LayerTypeDOCSISDscReq
+func (docsis *DOCSISDscReq) LayerType() gopacket.LayerType {
+	return LayerTypeDOCSISDscReq
+}
+
+// DecodeFromBytes decodes the given bytes into this layer.
+func (docsis *DOCSISDscReq) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
+	if err := docsis.parseDynamicService(data, false, 0, false); err != nil {
+		return err
+	}
+
+	docsis.Contents = data[:]
+	docsis.Payload = data[:0]
+
+	return nil
+}
+
+// CanDecode returns the set of layer types that this DecodingLayer can decode.
+func (docsis *DOCSISDscReq) CanDecode() gopacket.LayerClass {
+	return LayerTypeDOCSISDscReq
+}
+
+// NextLayerType returns the layer type contained by this DecodingLayer.
+func (docsis *DOCSISDscReq) NextLayerType() gopacket.LayerType {
+	return gopacket.LayerTypePayload
+}
+
+func decodeDOCSISDscReq(data []byte, p gopacket.PacketBuilder) error {
+	docsis := &DOCSISDscReq{}
+	err := docsis.DecodeFromBytes(data, p)
+	if err != nil {
+		return err
+	}
+	p.AddLayer(docsis)
+
+	return p.NextDecoder(docsis.NextLayerType())
+}
+
+// DOCSISDscRsp is a DOCSIS Management Dynamic Service Change Response.
+type DOCSISDscRsp struct {
+	layers.BaseLayer
+	DOCSISBaseDynamicService
+}
+
+// LayerType returns LayerTypeDOCSISDscRsp
+func (docsis *DOCSISDscRsp) LayerType() gopacket.LayerType {
+	return LayerTypeDOCSISDscRsp
+}
+
+// DecodeFromBytes decodes the given bytes into this layer.
+func (docsis *DOCSISDscRsp) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
+	if err := docsis.parseDynamicService(data, true, 0, false); err != nil {
+		return err
+	}
+
+	docsis.Contents = data[:]
+	docsis.Payload = data[:0]
+
+	return nil
+}
+
+// CanDecode returns the set of layer types that this DecodingLayer can decode.
+func (docsis *DOCSISDscRsp) CanDecode() gopacket.LayerClass {
+	return LayerTypeDOCSISDscRsp
+}
+
+// NextLayerType returns the layer type contained by this DecodingLayer.
+func (docsis *DOCSISDscRsp) NextLayerType() gopacket.LayerType {
+	return gopacket.LayerTypePayload
+}
+
+func decodeDOCSISDscRsp(data []byte, p gopacket.PacketBuilder) error {
+	docsis := &DOCSISDscRsp{}
+	err := docsis.DecodeFromBytes(data, p)
+	if err != nil {
+		return err
+	}
+	p.AddLayer(docsis)
+
+	return p.NextDecoder(docsis.NextLayerType())
+}
+
+// DOCSISDscAck is a DOCSIS Management Dynamic Service Change Acknowledge.
+type DOCSISDscAck struct {
+	layers.BaseLayer
+	DOCSISBaseDynamicService
+}
+
+// LayerType returns LayerTypeDOCSISDscAck
+func (docsis *DOCSISDscAck) LayerType() gopacket.LayerType {
+	return LayerTypeDOCSISDscAck
+}
+
+// DecodeFromBytes decodes the given bytes into this layer.
+func (docsis *DOCSISDscAck) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
+	if err := docsis.parseDynamicService(data, true, 0, false); err != nil {
+		return err
+	}
+
+	docsis.Contents = data[:]
+	docsis.Payload = data[:0]
+
+	return nil
+}
+
+// CanDecode returns the set of layer types that this DecodingLayer can decode.
+func (docsis *DOCSISDscAck) CanDecode() gopacket.LayerClass {
+	return LayerTypeDOCSISDscAck
+}
+
+// NextLayerType returns the layer type contained by this DecodingLayer.
+func (docsis *DOCSISDscAck) NextLayerType() gopacket.LayerType {
+	return gopacket.LayerTypePayload
+}
+
+func decodeDOCSISDscAck(data []byte, p gopacket.PacketBuilder) error {
+	docsis := &DOCSISDscAck{}
+	err := docsis.DecodeFromBytes(data, p)
+	if err != nil {
+		return err
+	}
+	p.AddLayer(docsis)
+
+	return p.NextDecoder(docsis.NextLayerType())
+}
+
+// DOCSISDsdReq is a DOCSIS Management Dynamic Service Deletion Request.
+type DOCSISDsdReq struct {
+	layers.BaseLayer
+	DOCSISBaseDynamicService
+}
+
+// LayerType returns LayerTypeDOCSISDsdReq
+func (docsis *DOCSISDsdReq) LayerType() gopacket.LayerType {
+	return LayerTypeDOCSISDsdReq
+}
+
+// DecodeFromBytes decodes the given bytes into this layer.
+func (docsis *DOCSISDsdReq) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
+	if err := docsis.parseDynamicService(data, false, 2, true); err != nil {
+		return err
+	}
+
+	docsis.Contents = data[:]
+	docsis.Payload = data[:0]
+
+	return nil
+}
+
+// CanDecode returns the set of layer types that this DecodingLayer can decode.
+func (docsis *DOCSISDsdReq) CanDecode() gopacket.LayerClass {
+	return LayerTypeDOCSISDsdReq
+}
+
+// NextLayerType returns the layer type contained by this DecodingLayer.
+func (docsis *DOCSISDsdReq) NextLayerType() gopacket.LayerType {
+	return gopacket.LayerTypePayload
+}
+
+func decodeDOCSISDsdReq(data []byte, p gopacket.PacketBuilder) error {
+	docsis := &DOCSISDsdReq{}
+	err := docsis.DecodeFromBytes(data, p)
+	if err != nil {
+		return err
+	}
+	p.AddLayer(docsis)
+
+	return p.NextDecoder(docsis.NextLayerType())
+}
+
+// DOCSISDsdRsp is a DOCSIS Management Dynamic Service Deletion Response.
+type DOCSISDsdRsp struct {
+	layers.BaseLayer
+	DOCSISBaseDynamicService
+}
+
+// LayerType returns LayerTypeDOCSISDsdRsp
+func (docsis *DOCSISDsdRsp) LayerType() gopacket.LayerType {
+	return LayerTypeDOCSISDsdRsp
+}
+
+// DecodeFromBytes decodes the given bytes into this layer.
+func (docsis *DOCSISDsdRsp) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
+	if err := docsis.parseDynamicService(data, true, 1, false); err != nil {
+		return err
+	}
+
+	docsis.Contents = data[:]
+	docsis.Payload = data[:0]
+
+	return nil
+}
+
+// CanDecode returns the set of layer types that this DecodingLayer can decode.
+func (docsis *DOCSISDsdRsp) CanDecode() gopacket.LayerClass {
+	return LayerTypeDOCSISDsdRsp
+}
+
+// NextLayerType returns the layer type contained by this DecodingLayer.
+func (docsis *DOCSISDsdRsp) NextLayerType() gopacket.LayerType {
+	return gopacket.LayerTypePayload
+}
+
+func decodeDOCSISDsdRsp(data []byte, p gopacket.PacketBuilder) error {
+	docsis := &DOCSISDsdRsp{}
+	err := docsis.DecodeFromBytes(data, p)
+	if err != nil {
+		return err
+	}
+	p.AddLayer(docsis)
+
+	return p.NextDecoder(docsis.NextLayerType())
+}
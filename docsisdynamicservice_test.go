@@ -0,0 +1,105 @@
+package main
+
+import (
+	"net"
+	"testing"
+)
+
+func TestDOCSISDsdReqDecode(t *testing.T) {
+	data := []byte{
+		0x00, 0x07, // transaction id
+		0x00, 0x00, // reserved
+		0x00, 0x00, 0x01, 0x2c, // sfid
+		0x18, 0x04, 0x01, 0x02, 0x00, 0x01, // upstream service flow reference 1
+	}
+
+	dsd := &DOCSISDsdReq{}
+	if err := dsd.DecodeFromBytes(data, nil); err != nil {
+		t.Fatal(err)
+	}
+	if dsd.TransactionID != 7 {
+		t.Errorf("transaction id is %d, want 7", dsd.TransactionID)
+	}
+	if dsd.SFID != 300 {
+		t.Errorf("sfid is %d, want 300", dsd.SFID)
+	}
+	if len(dsd.TLVs) != 1 || dsd.TLVs[0].Type != 24 {
+		t.Errorf("tlvs are %v, want one upstream service flow", dsd.TLVs)
+	}
+
+	if err := dsd.DecodeFromBytes(data[:7], nil); err == nil {
+		t.Error("truncated sfid decoded without error")
+	}
+}
+
+func TestServiceFlowTrackerLifecycle(t *testing.T) {
+	mac := net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}
+
+	dsaReq := &DOCSISDsaReq{}
+	mustDecode(t, dsaReq.DecodeFromBytes([]byte{
+		0x00, 0x01,
+		0x18, 0x07, 0x01, 0x02, 0x00, 0x01, 0x07, 0x01, 0x02, // reference 1, priority 2
+	}, nil))
+	dsaRsp := &DOCSISDsaRsp{}
+	mustDecode(t, dsaRsp.DecodeFromBytes([]byte{
+		0x00, 0x01, 0x00,
+		0x18, 0x0a, 0x01, 0x02, 0x00, 0x01, 0x02, 0x04, 0x00, 0x00, 0x00, 0x64, // reference 1, sfid 100
+	}, nil))
+	dscReq := &DOCSISDscReq{}
+	mustDecode(t, dscReq.DecodeFromBytes([]byte{
+		0x00, 0x02,
+		0x18, 0x09, 0x02, 0x04, 0x00, 0x00, 0x00, 0x64, 0x07, 0x01, 0x05, // sfid 100, priority 5
+	}, nil))
+	dscRsp := &DOCSISDscRsp{}
+	mustDecode(t, dscRsp.DecodeFromBytes([]byte{0x00, 0x02, 0x00}, nil))
+	dsdReq := &DOCSISDsdReq{}
+	mustDecode(t, dsdReq.DecodeFromBytes([]byte{0x00, 0x03, 0x00, 0x00, 0x00, 0x00, 0x00, 0x64}, nil))
+	dsdRsp := &DOCSISDsdRsp{}
+	mustDecode(t, dsdRsp.DecodeFromBytes([]byte{0x00, 0x03, 0x00, 0x00}, nil))
+
+	tracker := newServiceFlowTracker()
+	var changes []DynamicServiceKind
+	tracker.OnChange = func(mac net.HardwareAddr, kind DynamicServiceKind, flow ServiceFlow) {
+		if flow.SFID != 100 {
+			t.Errorf("change of sfid %d, want 100", flow.SFID)
+		}
+		changes = append(changes, kind)
+	}
+
+	tracker.AddRequest(mac, DynamicServiceAdd, &dsaReq.DOCSISBaseDynamicService)
+	tracker.AddConfirmation(mac, DynamicServiceAdd, &dsaRsp.DOCSISBaseDynamicService)
+	flows := tracker.Flows(mac)
+	if len(flows) != 1 || flows[0].SFID != 100 || flows[0].TrafficPriority != 2 {
+		t.Fatalf("flows after DSA are %+v, want sfid 100 with priority 2", flows)
+	}
+
+	tracker.AddRequest(mac, DynamicServiceChange, &dscReq.DOCSISBaseDynamicService)
+	tracker.AddConfirmation(mac, DynamicServiceChange, &dscRsp.DOCSISBaseDynamicService)
+	flows = tracker.Flows(mac)
+	if len(flows) != 1 || flows[0].TrafficPriority != 5 {
+		t.Fatalf("flows after DSC are %+v, want priority 5", flows)
+	}
+
+	tracker.AddRequest(mac, DynamicServiceDelete, &dsdReq.DOCSISBaseDynamicService)
+	tracker.AddConfirmation(mac, DynamicServiceDelete, &dsdRsp.DOCSISBaseDynamicService)
+	if flows = tracker.Flows(mac); len(flows) != 0 {
+		t.Fatalf("flows after DSD are %+v, want none", flows)
+	}
+
+	want := []DynamicServiceKind{DynamicServiceAdd, DynamicServiceChange, DynamicServiceDelete}
+	if len(changes) != len(want) {
+		t.Fatalf("changes are %v, want %v", changes, want)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Errorf("change %d is %v, want %v", i, changes[i], want[i])
+		}
+	}
+}
+
+func mustDecode(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// LayerTypeDOCSISIsolation type registration
+var LayerTypeDOCSISIsolation = gopacket.RegisterLayerType(1006, gopacket.LayerTypeMetadata{Name: "DOCSIS Isolation PDU", Decoder: gopacket.DecodeFunc(decodeDOCSISIsolation)})
+
+// DOCSISIsolation is a DOCSIS isolation PDU header.
+// The isolation PDU has the same format as a packet PDU but is kept apart from regular traffic (e.g. L2VPN or DSG).
+type DOCSISIsolation struct {
+	layers.BaseLayer
+	SrcMAC, DstMAC net.HardwareAddr
+	EthernetType   layers.EthernetType
+}
+
+// LayerType returns LayerTypeDOCSISIsolation
+func (docsis *DOCSISIsolation) LayerType() gopacket.LayerType {
+	return LayerTypeDOCSISIsolation
+}
+
+// DecodeFromBytes decodes the given bytes into this layer.
+func (docsis *DOCSISIsolation) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
+	if len(data) < 14 {
+		return fmt.Errorf("docsis isolation pdu too small")
+	}
+
+	docsis.DstMAC = net.HardwareAddr(data[0:6])
+	docsis.SrcMAC = net.HardwareAddr(data[6:12])
+	docsis.EthernetType = layers.EthernetType(binary.BigEndian.Uint16(data[12:14]))
+	docsis.Contents = data[:14]
+	docsis.Payload = data[14:]
+
+	return nil
+}
+
+// LinkFlow returns a flow of the source and destination MAC addresses.
+func (docsis *DOCSISIsolation) LinkFlow() gopacket.Flow {
+	return gopacket.NewFlow(layers.EndpointMAC, docsis.SrcMAC, docsis.DstMAC)
+}
+
+// CanDecode returns the set of layer types that this DecodingLayer can decode.
+func (docsis *DOCSISIsolation) CanDecode() gopacket.LayerClass {
+	return LayerTypeDOCSISIsolation
+}
+
+// NextLayerType returns the layer type contained by this DecodingLayer.
+func (docsis *DOCSISIsolation) NextLayerType() gopacket.LayerType {
+	// values below 0x600 are a length field and the payload starts with a LLC header
+	if docsis.EthernetType < 0x600 {
+		return layers.LayerTypeLLC
+	}
+
+	return docsis.EthernetType.LayerType()
+}
+
+func decodeDOCSISIsolation(data []byte, p gopacket.PacketBuilder) error {
+	docsis := &DOCSISIsolation{}
+	err := docsis.DecodeFromBytes(data, p)
+	if err != nil {
+		return err
+	}
+	p.AddLayer(docsis)
+	p.SetLinkLayer(docsis)
+
+	return p.NextDecoder(docsis.NextLayerType())
+}
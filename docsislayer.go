@@ -2,7 +2,9 @@ package main
 
 import (
 	"encoding/binary"
+	"errors"
 	"fmt"
+	"hash/crc32"
 
 	"github.com/google/gopacket"
 	"github.com/google/gopacket/layers"
@@ -12,6 +14,12 @@ import (
 // LayerTypeDOCSIS type registration
 var LayerTypeDOCSIS = gopacket.RegisterLayerType(1000, gopacket.LayerTypeMetadata{Name: "DOCSIS", Decoder: gopacket.DecodeFunc(decodeDOCSIS)})
 
+// ErrDOCSISHCSMismatch is returned if the header check sequence of a packet is wrong.
+var ErrDOCSISHCSMismatch = errors.New("header check sequence doesn't match")
+
+// ErrDOCSISTruncated is returned if a packet is smaller than advertised by its header.
+var ErrDOCSISTruncated = errors.New("docsis packet smaller than advertised by header")
+
 // DOCSIS is a DOCSIS packet header.
 type DOCSIS struct {
 	layers.BaseLayer
@@ -19,9 +27,46 @@ type DOCSIS struct {
 	FCParm               uint8
 	ExtHdrPresent        bool
 	ExtHdr               []byte
+	Length               uint16
 	Encrypted            bool
 	CheckSequence        uint16
 	CheckSequenceCorrect bool
+	// Baseline privacy extended header
+	BPIVersion     uint8
+	BPIKeySequence uint8
+	BPIToggle      bool
+	BPISAID        uint16
+	// Decrypted is set if an encrypted payload was decrypted by the Decryptor.
+	// The payload then references a buffer of the Decryptor.
+	Decrypted bool
+	// Decryptor is optional and used to decrypt BPI+ encrypted payloads if set.
+	Decryptor *BPIDecryptor
+	// PDUCRC is the CRC-32 of the Ethernet frame carried in a packet PDU.
+	// It isn't verified for encrypted frames unless they were decrypted as it's part of the ciphertext.
+	PDUCRC        uint32
+	PDUCRCCorrect bool
+	// Downstream Service extended header, used for channel bonding
+	DSIDPresent                 bool
+	DSID                        uint32
+	TrafficPriority             uint8
+	SequenceChangeCount         uint8
+	PacketSequenceNumberPresent bool
+	PacketSequenceNumber        uint16
+	// Truncated is set if the packet is smaller than advertised by its header.
+	Truncated bool
+	// Counters is optional and gets updated on every decoded packet if set.
+	Counters *DOCSISCounters
+	// Lenient keeps packets with a wrong header check sequence or a truncated payload
+	// instead of returning an error. The header fields are still decoded.
+	Lenient bool
+}
+
+// DOCSISCounters keeps statistics about decoded DOCSIS packets.
+type DOCSISCounters struct {
+	PDUs         uint64
+	PDUCRCErrors uint64
+	HCSErrors    uint64
+	Truncated    uint64
 }
 
 // LayerType returns LayerTypeDOCSIS
@@ -42,14 +87,40 @@ func (docsis *DOCSIS) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) e
 	// reset attributes
 	docsis.ExtHdr = docsis.ExtHdr[:0]
 	docsis.Encrypted = false
+	docsis.BPIVersion = 0
+	docsis.BPIKeySequence = 0
+	docsis.BPIToggle = false
+	docsis.BPISAID = 0
+	docsis.Decrypted = false
+	docsis.PDUCRC = 0
+	docsis.PDUCRCCorrect = false
+	docsis.Truncated = false
+	docsis.DSIDPresent = false
+	docsis.DSID = 0
+	docsis.TrafficPriority = 0
+	docsis.SequenceChangeCount = 0
+	docsis.PacketSequenceNumberPresent = false
+	docsis.PacketSequenceNumber = 0
 
 	// skip header for payload
 	payloadStart := uint(6)
 	// length field defines the length of extender header + payload
-	payloadEnd := uint(payloadStart + uint(binary.BigEndian.Uint16(data[2:4])))
+	docsis.Length = binary.BigEndian.Uint16(data[2:4])
+	payloadEnd := uint(payloadStart + uint(docsis.Length))
 
 	if uint(len(data)) < payloadEnd {
-		return fmt.Errorf("docsis packet smaller than advertised by header")
+		if docsis.Counters != nil {
+			docsis.Counters.Truncated++
+		}
+		if !docsis.Lenient {
+			return ErrDOCSISTruncated
+		}
+
+		docsis.Truncated = true
+		payloadEnd = uint(len(data))
+		if df != nil {
+			df.SetTruncated()
+		}
 	}
 
 	if docsis.ExtHdrPresent {
@@ -74,8 +145,10 @@ func (docsis *DOCSIS) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) e
 			// TODO: properly parse extended headers
 			docsis.ExtHdr = append(docsis.ExtHdr, ehdrType)
 
-			if ehdrType == 4 && ehdrLen >= 3 && (data[i+2]&0x80) == 0x80 {
-				docsis.Encrypted = true
+			if ehdrType == 4 && ehdrLen >= 4 {
+				docsis.parseBPIExtHdr(data[i+1 : i+ehdrLen])
+			} else if ehdrType == 8 && ehdrLen >= 4 {
+				docsis.parseDSExtHdr(data[i+1 : i+ehdrLen])
 			}
 		}
 	}
@@ -87,12 +160,143 @@ func (docsis *DOCSIS) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) e
 
 	docsis.CheckSequenceCorrect = (docsis.CheckSequence == checkSequenceCalculated)
 	if !docsis.CheckSequenceCorrect {
-		return fmt.Errorf("header check sequence doesn't match")
+		if docsis.Counters != nil {
+			docsis.Counters.HCSErrors++
+		}
+		if !docsis.Lenient {
+			return ErrDOCSISHCSMismatch
+		}
 	}
 
 	docsis.Contents = data[:payloadStart]
 	docsis.Payload = data[payloadStart:payloadEnd]
 
+	if docsis.Encrypted && docsis.Decryptor != nil && docsis.CheckSequenceCorrect && !docsis.Truncated {
+		// without a key the frame is passed on encrypted. The same goes for a wrong key, like the latest key
+		// with the same toggle bit used for an unknown key sequence, as the PDU CRC doesn't match then.
+		if plaintext, err := docsis.Decryptor.Decrypt(docsis.BPISAID, docsis.BPIKeySequence, docsis.BPIToggle, docsis.Payload); err == nil {
+			if _, crcCorrect := pduCRC(plaintext); crcCorrect || !(docsis.isPacketPDU() || docsis.isIsolationPDU()) {
+				docsis.Payload = plaintext
+				docsis.Decrypted = true
+			}
+		}
+	}
+
+	// the CRC can only be trusted to be there if the header is intact
+	if (docsis.isPacketPDU() || docsis.isIsolationPDU()) && (!docsis.Encrypted || docsis.Decrypted) && docsis.CheckSequenceCorrect && !docsis.Truncated {
+		docsis.checkPDUCRC()
+	}
+
+	return nil
+}
+
+// parseBPIExtHdr parses the value of a baseline privacy extended header.
+func (docsis *DOCSIS) parseBPIExtHdr(value []byte) {
+	docsis.BPIKeySequence = (value[0] & 0xf0) >> 4 // 0b11110000
+	docsis.BPIVersion = value[0] & 0x0f            // 0b00001111
+	docsis.Encrypted = (value[1] & 0x80) == 0x80   // 0b10000000
+	docsis.BPIToggle = (value[1] & 0x40) == 0x40   // 0b01000000
+	docsis.BPISAID = uint16(value[1]&0x3f)<<8 | uint16(value[2])
+}
+
+// parseDSExtHdr parses the value of a Downstream Service extended header.
+func (docsis *DOCSIS) parseDSExtHdr(value []byte) {
+	docsis.DSIDPresent = true
+	docsis.TrafficPriority = (value[0] & 0xe0) >> 5 // 0b11100000
+	docsis.DSID = uint32(value[0]&0x0f)<<16 | uint32(binary.BigEndian.Uint16(value[1:3]))
+
+	// the packet sequence number is only present in the 5 byte variant
+	if len(value) >= 5 {
+		docsis.SequenceChangeCount = (value[0] & 0x10) >> 4 // 0b00010000
+		docsis.PacketSequenceNumberPresent = true
+		docsis.PacketSequenceNumber = binary.BigEndian.Uint16(value[3:5])
+	}
+}
+
+func (docsis *DOCSIS) isPacketPDU() bool {
+	return docsis.FCType == 0 && docsis.FCParm == 0
+}
+
+func (docsis *DOCSIS) isIsolationPDU() bool {
+	return docsis.FCType == 2
+}
+
+// checkPDUCRC verifies the Ethernet CRC at the end of the payload and strips it.
+func (docsis *DOCSIS) checkPDUCRC() {
+	if docsis.Counters != nil {
+		docsis.Counters.PDUs++
+	}
+
+	if len(docsis.Payload) < 4 {
+		if docsis.Counters != nil {
+			docsis.Counters.PDUCRCErrors++
+		}
+		return
+	}
+
+	docsis.PDUCRC, docsis.PDUCRCCorrect = pduCRC(docsis.Payload)
+	if !docsis.PDUCRCCorrect && docsis.Counters != nil {
+		docsis.Counters.PDUCRCErrors++
+	}
+
+	docsis.Payload = docsis.Payload[:len(docsis.Payload)-4]
+}
+
+// pduCRC returns the Ethernet CRC at the end of the payload and whether it matches the frame.
+func pduCRC(payload []byte) (uint32, bool) {
+	if len(payload) < 4 {
+		return 0, false
+	}
+
+	crcStart := len(payload) - 4
+	// the Ethernet FCS is transmitted least significant byte first
+	crc := binary.LittleEndian.Uint32(payload[crcStart:])
+	return crc, crc == crc32.ChecksumIEEE(payload[:crcStart])
+}
+
+// SerializeTo writes the serialized form of this layer into the SerializationBuffer.
+// Extended headers can't be serialized. For packet PDUs the Ethernet CRC is appended
+// if checksums are computed.
+func (docsis *DOCSIS) SerializeTo(b gopacket.SerializeBuffer, opts gopacket.SerializeOptions) error {
+	if docsis.ExtHdrPresent {
+		return fmt.Errorf("docsis extended headers can't be serialized")
+	}
+
+	if opts.ComputeChecksums && (docsis.isPacketPDU() || docsis.isIsolationPDU()) {
+		docsis.PDUCRC = crc32.ChecksumIEEE(b.Bytes())
+		docsis.PDUCRCCorrect = true
+		crcBytes, err := b.AppendBytes(4)
+		if err != nil {
+			return err
+		}
+		binary.LittleEndian.PutUint32(crcBytes, docsis.PDUCRC)
+	}
+
+	if opts.FixLengths {
+		payloadLen := len(b.Bytes())
+		if payloadLen > 0xffff {
+			return fmt.Errorf("docsis payload is too large")
+		}
+		docsis.Length = uint16(payloadLen)
+	}
+
+	bytes, err := b.PrependBytes(6)
+	if err != nil {
+		return err
+	}
+
+	bytes[0] = (docsis.FCType&0x03)<<6 | (docsis.FCParm&0x1f)<<1
+	bytes[1] = 0
+	binary.BigEndian.PutUint16(bytes[2:4], docsis.Length)
+
+	if opts.ComputeChecksums {
+		// the HCS is transmitted in the same byte order the decoder expects
+		checkSequenceLitteEndian := crc16.ChecksumCCITT(bytes[:4])
+		docsis.CheckSequence = (checkSequenceLitteEndian << 8) | (checkSequenceLitteEndian >> 8)
+		docsis.CheckSequenceCorrect = true
+	}
+	binary.BigEndian.PutUint16(bytes[4:6], docsis.CheckSequence)
+
 	return nil
 }
 
@@ -107,12 +311,20 @@ func (docsis *DOCSIS) NextLayerType() gopacket.LayerType {
 		return gopacket.LayerTypePayload
 	}
 
-	if docsis.FCType == 0 && docsis.FCParm == 0 {
-		if docsis.Encrypted {
+	if docsis.isPacketPDU() {
+		if docsis.Encrypted && !docsis.Decrypted {
 			return LayerTypeETHENC
 		}
 
 		return layers.LayerTypeEthernet
+	} else if docsis.FCType == 1 {
+		return LayerTypeDOCSISATM
+	} else if docsis.isIsolationPDU() {
+		if docsis.Encrypted && !docsis.Decrypted {
+			return LayerTypeETHENC
+		}
+
+		return LayerTypeDOCSISIsolation
 	} else if docsis.FCType == 3 {
 		return LayerTypeDOCSISManagement
 	}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"testing"
+
+	"github.com/howeyc/crc16"
+)
+
+// buildDOCSISFrame returns a DOCSIS frame with a valid HCS. The length field covers
+// the extended header and the payload.
+func buildDOCSISFrame(fc byte, ehdr []byte, payload []byte) []byte {
+	frame := []byte{fc, byte(len(ehdr)), 0, 0}
+	binary.BigEndian.PutUint16(frame[2:4], uint16(len(ehdr)+len(payload)))
+	frame = append(frame, ehdr...)
+
+	checkSequence := crc16.ChecksumCCITT(frame)
+	frame = append(frame, byte(checkSequence), byte(checkSequence>>8))
+
+	return append(frame, payload...)
+}
+
+// withEthernetCRC appends the Ethernet CRC to a frame.
+func withEthernetCRC(frame []byte) []byte {
+	crc := make([]byte, 4)
+	binary.LittleEndian.PutUint32(crc, crc32.ChecksumIEEE(frame))
+	return append(append([]byte(nil), frame...), crc...)
+}
+
+func TestDOCSISDownstreamServiceExtHdr(t *testing.T) {
+	ethernet := make([]byte, 60)
+	ethernet[12] = 0x08
+
+	tests := []struct {
+		name                        string
+		ehdr                        []byte
+		dsid                        uint32
+		trafficPriority             uint8
+		sequenceChangeCount         uint8
+		packetSequenceNumberPresent bool
+		packetSequenceNumber        uint16
+	}{
+		{
+			name:            "3 bytes",
+			ehdr:            []byte{0x83, 0xa1, 0x23, 0x45},
+			dsid:            0x12345,
+			trafficPriority: 5,
+		},
+		{
+			name:                        "5 bytes",
+			ehdr:                        []byte{0x85, 0x7b, 0xcd, 0xef, 0xbe, 0xef},
+			dsid:                        0xbcdef,
+			trafficPriority:             3,
+			sequenceChangeCount:         1,
+			packetSequenceNumberPresent: true,
+			packetSequenceNumber:        0xbeef,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var docsis DOCSIS
+			if err := docsis.DecodeFromBytes(buildDOCSISFrame(0x01, test.ehdr, withEthernetCRC(ethernet)), nil); err != nil {
+				t.Fatal(err)
+			}
+
+			if !docsis.DSIDPresent || docsis.DSID != test.dsid {
+				t.Errorf("DSID = %#x (present %v), want %#x", docsis.DSID, docsis.DSIDPresent, test.dsid)
+			}
+			if docsis.TrafficPriority != test.trafficPriority {
+				t.Errorf("TrafficPriority = %d, want %d", docsis.TrafficPriority, test.trafficPriority)
+			}
+			if docsis.SequenceChangeCount != test.sequenceChangeCount {
+				t.Errorf("SequenceChangeCount = %d, want %d", docsis.SequenceChangeCount, test.sequenceChangeCount)
+			}
+			if docsis.PacketSequenceNumberPresent != test.packetSequenceNumberPresent || docsis.PacketSequenceNumber != test.packetSequenceNumber {
+				t.Errorf("PacketSequenceNumber = %#x (present %v), want %#x", docsis.PacketSequenceNumber, docsis.PacketSequenceNumberPresent, test.packetSequenceNumber)
+			}
+			if !docsis.PDUCRCCorrect || len(docsis.Payload) != len(ethernet) {
+				t.Errorf("PDU CRC correct %v, payload length %d", docsis.PDUCRCCorrect, len(docsis.Payload))
+			}
+		})
+	}
+}
+
+func TestDOCSISPathVerifyExtHdrIsNotDownstreamService(t *testing.T) {
+	var docsis DOCSIS
+	frame := buildDOCSISFrame(0x01, []byte{0x93, 0x01, 0x02, 0x03}, withEthernetCRC(make([]byte, 60)))
+	if err := docsis.DecodeFromBytes(frame, nil); err != nil {
+		t.Fatal(err)
+	}
+	if docsis.DSIDPresent {
+		t.Error("DSID decoded from a DOCSIS path verify extended header")
+	}
+}
@@ -1,8 +1,11 @@
 package main
 
 import (
+	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
+	"hash/crc32"
 	"net"
 
 	"github.com/google/gopacket"
@@ -12,15 +15,176 @@ import (
 // LayerTypeDOCSISManagement type registration
 var LayerTypeDOCSISManagement = gopacket.RegisterLayerType(1002, gopacket.LayerTypeMetadata{Name: "DOCSIS Management", Decoder: gopacket.DecodeFunc(decodeDOCSISManagement)})
 
+// DocsisManagementSync code for DOCSIS Management Time Synchronization
+const DocsisManagementSync = 1
+
+// DocsisManagementUCD code for DOCSIS Management Upstream Channel Descriptor
+const DocsisManagementUCD = 2
+
+// DocsisManagementMap code for DOCSIS Management Upstream Bandwidth Allocation
+const DocsisManagementMap = 3
+
+// DocsisManagementRngReq code for DOCSIS Management Ranging Request
+const DocsisManagementRngReq = 4
+
+// DocsisManagementRngRsp code for DOCSIS Management Ranging Response
+const DocsisManagementRngRsp = 5
+
+// DocsisManagementRegReq code for DOCSIS Management Registration Request
+const DocsisManagementRegReq = 6
+
 // DocsisManagementRegRsp code for DOCSIS Management Registration Response
 const DocsisManagementRegRsp = 7
 
+// DocsisManagementUccReq code for DOCSIS Management Upstream Channel Change Request
+const DocsisManagementUccReq = 8
+
+// DocsisManagementUccRsp code for DOCSIS Management Upstream Channel Change Response
+const DocsisManagementUccRsp = 9
+
+// DocsisManagementBpkmReq code for Baseline Privacy Key Management Request
+const DocsisManagementBpkmReq = 12
+
 // DocsisManagementBpkmRsp code for Baseline Privacy Key Management Response
 const DocsisManagementBpkmRsp = 13
 
+// DocsisManagementRegAck code for DOCSIS Management Registration Acknowledge
+const DocsisManagementRegAck = 14
+
+// DocsisManagementDsaReq code for DOCSIS Management Dynamic Service Addition Request
+const DocsisManagementDsaReq = 15
+
+// DocsisManagementDsaRsp code for DOCSIS Management Dynamic Service Addition Response
+const DocsisManagementDsaRsp = 16
+
+// DocsisManagementDsaAck code for DOCSIS Management Dynamic Service Addition Acknowledge
+const DocsisManagementDsaAck = 17
+
+// DocsisManagementDscReq code for DOCSIS Management Dynamic Service Change Request
+const DocsisManagementDscReq = 18
+
+// DocsisManagementDscRsp code for DOCSIS Management Dynamic Service Change Response
+const DocsisManagementDscRsp = 19
+
+// DocsisManagementDscAck code for DOCSIS Management Dynamic Service Change Acknowledge
+const DocsisManagementDscAck = 20
+
+// DocsisManagementDsdReq code for DOCSIS Management Dynamic Service Deletion Request
+const DocsisManagementDsdReq = 21
+
+// DocsisManagementDsdRsp code for DOCSIS Management Dynamic Service Deletion Response
+const DocsisManagementDsdRsp = 22
+
+// DocsisManagementDccReq code for DOCSIS Management Dynamic Channel Change Request
+const DocsisManagementDccReq = 23
+
+// DocsisManagementDccRsp code for DOCSIS Management Dynamic Channel Change Response
+const DocsisManagementDccRsp = 24
+
+// DocsisManagementDccAck code for DOCSIS Management Dynamic Channel Change Acknowledge
+const DocsisManagementDccAck = 25
+
+// DocsisManagementUCD29 code for DOCSIS Management Upstream Channel Descriptor for DOCSIS 2.0 channels
+const DocsisManagementUCD29 = 29
+
+// DocsisManagementMDD code for DOCSIS Management MAC Domain Descriptor
+const DocsisManagementMDD = 33
+
+// DocsisManagementBInitRngReq code for DOCSIS Management Bonded Initial Ranging Request
+const DocsisManagementBInitRngReq = 34
+
+// DocsisManagementUCD35 code for DOCSIS Management Upstream Channel Descriptor for DOCSIS 3.0 channels
+const DocsisManagementUCD35 = 35
+
+// DocsisManagementDbcReq code for DOCSIS Management Dynamic Bonding Change Request
+const DocsisManagementDbcReq = 36
+
+// DocsisManagementDbcRsp code for DOCSIS Management Dynamic Bonding Change Response
+const DocsisManagementDbcRsp = 37
+
+// DocsisManagementDbcAck code for DOCSIS Management Dynamic Bonding Change Acknowledge
+const DocsisManagementDbcAck = 38
+
+// DocsisManagementCMStatus code for DOCSIS Management CM Status Report
+const DocsisManagementCMStatus = 41
+
+// DocsisManagementCMCtrlReq code for DOCSIS Management CM Control Request
+const DocsisManagementCMCtrlReq = 42
+
+// DocsisManagementCMCtrlRsp code for DOCSIS Management CM Control Response
+const DocsisManagementCMCtrlRsp = 43
+
+// DocsisManagementRegReqMp code for DOCSIS Management Multipart Registration Request
+const DocsisManagementRegReqMp = 44
+
 // DocsisManagementRegRspMp code for DOCSIS Management Multipart Registration Response
 const DocsisManagementRegRspMp = 45
 
+// DocsisManagementOCD code for DOCSIS Management OFDM Channel Descriptor
+const DocsisManagementOCD = 49
+
+// DocsisManagementDPD code for DOCSIS Management Downstream Profile Descriptor
+const DocsisManagementDPD = 50
+
+// DocsisManagementUCD51 code for DOCSIS Management Upstream Channel Descriptor for OFDMA channels
+const DocsisManagementUCD51 = 51
+
+// managementMinVersions is the lowest version each management message type was defined with.
+var managementMinVersions = map[uint8]uint8{
+	DocsisManagementRegAck:      2,
+	DocsisManagementDsaReq:      2,
+	DocsisManagementDsaRsp:      2,
+	DocsisManagementDsaAck:      2,
+	DocsisManagementDscReq:      2,
+	DocsisManagementDscRsp:      2,
+	DocsisManagementDscAck:      2,
+	DocsisManagementDsdReq:      2,
+	DocsisManagementDsdRsp:      2,
+	DocsisManagementDccReq:      2,
+	DocsisManagementDccRsp:      2,
+	DocsisManagementDccAck:      2,
+	DocsisManagementUCD29:       3,
+	DocsisManagementMDD:         4,
+	DocsisManagementBInitRngReq: 4,
+	DocsisManagementUCD35:       4,
+	DocsisManagementDbcReq:      4,
+	DocsisManagementDbcRsp:      4,
+	DocsisManagementDbcAck:      4,
+	DocsisManagementCMStatus:    4,
+	DocsisManagementCMCtrlReq:   4,
+	DocsisManagementCMCtrlRsp:   4,
+	DocsisManagementRegReqMp:    4,
+	DocsisManagementRegRspMp:    4,
+	DocsisManagementOCD:         5,
+	DocsisManagementDPD:         5,
+	DocsisManagementUCD51:       5,
+}
+
+// docsisAllCMsMAC is the well-known multicast address reaching all cable modems.
+var docsisAllCMsMAC = net.HardwareAddr{0x01, 0xe0, 0x2f, 0x00, 0x00, 0x01}
+
+// ErrDOCSISManagementLLC is returned if the LLC header isn't the null SAP with an unnumbered information frame.
+var ErrDOCSISManagementLLC = errors.New("docsis management packet has an invalid llc header")
+
+// managementMaxVersion is the newest management message version. All versions up to it share the 20 byte header.
+const managementMaxVersion = 5
+
+// DOCSISManagementVersionError is returned if the version is too old for the message type
+// or newer than any known version.
+type DOCSISManagementVersionError struct {
+	Type       uint8
+	Version    uint8
+	MinVersion uint8
+	MaxVersion uint8
+}
+
+func (err *DOCSISManagementVersionError) Error() string {
+	if err.Version > err.MaxVersion {
+		return fmt.Sprintf("docsis management message type %d has unknown version %d, the newest is %d", err.Type, err.Version, err.MaxVersion)
+	}
+	return fmt.Sprintf("docsis management message type %d requires version %d, got %d", err.Type, err.MinVersion, err.Version)
+}
+
 // DOCSISManagement is a DOCSIS Management packet header.
 type DOCSISManagement struct {
 	layers.BaseLayer
@@ -32,6 +196,8 @@ type DOCSISManagement struct {
 	Version        byte
 	Type           byte
 	Reserved       byte
+	// Lenient keeps messages with an invalid LLC header or version instead of returning an error.
+	Lenient bool
 }
 
 // LayerType returns LayerTypeDOCSISManagement
@@ -67,12 +233,101 @@ func (docsisManagement *DOCSISManagement) DecodeFromBytes(data []byte, df gopack
 		return fmt.Errorf("docsis management packet is too small for the payload")
 	}
 
+	if err := docsisManagement.validate(); err != nil && !docsisManagement.Lenient {
+		return err
+	}
+
 	docsisManagement.Contents = data[:payloadStart]
 	docsisManagement.Payload = data[payloadStart:payloadEnd]
 
 	return nil
 }
 
+// SerializeTo writes the serialized form of this layer into the SerializationBuffer.
+// The CRC of the message is appended if checksums are computed.
+func (docsisManagement *DOCSISManagement) SerializeTo(b gopacket.SerializeBuffer, opts gopacket.SerializeOptions) error {
+	if len(docsisManagement.DstMAC) != 6 || len(docsisManagement.SrcMAC) != 6 {
+		return fmt.Errorf("docsis management mac addresses must be 6 bytes")
+	}
+
+	payloadLen := len(b.Bytes())
+	bytes, err := b.PrependBytes(20)
+	if err != nil {
+		return err
+	}
+
+	if opts.FixLengths {
+		// LLC header, version, type and reserved are part of the message length
+		docsisManagement.MessageLength = uint16(6 + payloadLen)
+		docsisManagement.DSAP = 0
+		docsisManagement.SSAP = 0
+		docsisManagement.Control = 0x03
+	}
+
+	copy(bytes[0:6], docsisManagement.DstMAC)
+	copy(bytes[6:12], docsisManagement.SrcMAC)
+	binary.BigEndian.PutUint16(bytes[12:14], docsisManagement.MessageLength)
+	bytes[14] = docsisManagement.DSAP
+	bytes[15] = docsisManagement.SSAP
+	bytes[16] = docsisManagement.Control
+	bytes[17] = docsisManagement.Version
+	bytes[18] = docsisManagement.Type
+	bytes[19] = docsisManagement.Reserved
+
+	if opts.ComputeChecksums {
+		crc := crc32.ChecksumIEEE(b.Bytes())
+		crcBytes, err := b.AppendBytes(4)
+		if err != nil {
+			return err
+		}
+		binary.LittleEndian.PutUint32(crcBytes, crc)
+	}
+
+	return nil
+}
+
+// validate checks the LLC header and the version of the message type.
+func (docsisManagement *DOCSISManagement) validate() error {
+	if docsisManagement.DSAP != 0 || docsisManagement.SSAP != 0 || docsisManagement.Control != 0x03 {
+		return ErrDOCSISManagementLLC
+	}
+
+	minVersion, ok := managementMinVersions[docsisManagement.Type]
+	if !ok {
+		minVersion = 1
+	}
+	if docsisManagement.Version < minVersion || docsisManagement.Version > managementMaxVersion {
+		return &DOCSISManagementVersionError{
+			Type:       docsisManagement.Type,
+			Version:    docsisManagement.Version,
+			MinVersion: minVersion,
+			MaxVersion: managementMaxVersion,
+		}
+	}
+
+	return nil
+}
+
+// IsBroadcastToAllCMs returns true if the message is sent to the all cable modems multicast address.
+func (docsisManagement *DOCSISManagement) IsBroadcastToAllCMs() bool {
+	return bytes.Equal(docsisManagement.DstMAC, docsisAllCMsMAC)
+}
+
+// IsMulticast returns true if the destination is a multicast or broadcast address.
+func (docsisManagement *DOCSISManagement) IsMulticast() bool {
+	return len(docsisManagement.DstMAC) > 0 && (docsisManagement.DstMAC[0]&0x01) == 0x01
+}
+
+// IsUnicast returns true if the message is addressed to a single station.
+func (docsisManagement *DOCSISManagement) IsUnicast() bool {
+	return len(docsisManagement.DstMAC) > 0 && !docsisManagement.IsMulticast()
+}
+
+// LinkFlow returns a flow of the source and destination MAC addresses.
+func (docsisManagement *DOCSISManagement) LinkFlow() gopacket.Flow {
+	return gopacket.NewFlow(layers.EndpointMAC, docsisManagement.SrcMAC, docsisManagement.DstMAC)
+}
+
 // CanDecode returns the set of layer types that this DecodingLayer can decode.
 func (docsisManagement *DOCSISManagement) CanDecode() gopacket.LayerClass {
 	return LayerTypeDOCSISManagement
@@ -80,15 +335,8 @@ func (docsisManagement *DOCSISManagement) CanDecode() gopacket.LayerClass {
 
 // NextLayerType returns the layer type contained by this DecodingLayer.
 func (docsisManagement *DOCSISManagement) NextLayerType() gopacket.LayerType {
-	if docsisManagement.Type == DocsisManagementRegRsp {
-		return LayerTypeDOCSISRegRsp
-	} else if docsisManagement.Type == DocsisManagementBpkmRsp {
-		return LayerTypeDOCSISBpkmRsp
-	} else if docsisManagement.Type == DocsisManagementRegRspMp {
-		return LayerTypeDOCSISRegRspMp
-	}
-
-	return gopacket.LayerTypePayload
+	layerType, _ := ManagementLayerType(docsisManagement.Type, docsisManagement.Version)
+	return layerType
 }
 
 func decodeDOCSISManagement(data []byte, p gopacket.PacketBuilder) error {
@@ -98,6 +346,7 @@ func decodeDOCSISManagement(data []byte, p gopacket.PacketBuilder) error {
 		return err
 	}
 	p.AddLayer(docsisManagement)
+	p.SetLinkLayer(docsisManagement)
 
 	return p.NextDecoder(docsisManagement.NextLayerType())
 }
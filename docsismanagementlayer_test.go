@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+func buildManagementHeader(version, messageType uint8, llc [3]byte, payload []byte) []byte {
+	data := make([]byte, 20, 20+len(payload))
+	copy(data[0:6], testModemMAC)
+	copy(data[6:12], testCMTSMAC)
+	binary.BigEndian.PutUint16(data[12:14], uint16(6+len(payload)))
+	copy(data[14:17], llc[:])
+	data[17] = version
+	data[18] = messageType
+
+	return append(data, payload...)
+}
+
+func TestDOCSISManagementLLC(t *testing.T) {
+	data := buildManagementHeader(1, DocsisManagementRegRsp, [3]byte{0xaa, 0xaa, 0x03}, []byte{0x00, 0x01, 0x00})
+
+	docsisManagement := &DOCSISManagement{}
+	if err := docsisManagement.DecodeFromBytes(data, nil); err != ErrDOCSISManagementLLC {
+		t.Errorf("error is %v, want %v", err, ErrDOCSISManagementLLC)
+	}
+
+	docsisManagement.Lenient = true
+	if err := docsisManagement.DecodeFromBytes(data, nil); err != nil {
+		t.Fatalf("lenient decode failed: %v", err)
+	}
+	if docsisManagement.DSAP != 0xaa || len(docsisManagement.Payload) != 3 {
+		t.Errorf("lenient decode has DSAP %#x and payload %x", docsisManagement.DSAP, docsisManagement.Payload)
+	}
+}
+
+func TestDOCSISManagementVersion(t *testing.T) {
+	llc := [3]byte{0x00, 0x00, 0x03}
+
+	for _, test := range []struct {
+		messageType uint8
+		version     uint8
+		minVersion  uint8
+	}{
+		{DocsisManagementRegRsp, 1, 0},
+		{DocsisManagementRegAck, 1, 2},
+		{DocsisManagementRegAck, 2, 0},
+		{DocsisManagementDsaReq, 1, 2},
+		{DocsisManagementDsdRsp, 2, 0},
+		{DocsisManagementMDD, 3, 4},
+		{DocsisManagementOCD, 5, 0},
+		{DocsisManagementRegRsp, 6, 1},
+		{DocsisManagementOCD, 200, 5},
+	} {
+		data := buildManagementHeader(test.version, test.messageType, llc, []byte{0x00, 0x00, 0x00, 0x00})
+
+		docsisManagement := &DOCSISManagement{}
+		err := docsisManagement.DecodeFromBytes(data, nil)
+
+		var versionErr *DOCSISManagementVersionError
+		if test.minVersion == 0 {
+			if err != nil {
+				t.Errorf("type %d version %d: %v", test.messageType, test.version, err)
+			}
+		} else if !errors.As(err, &versionErr) {
+			t.Errorf("type %d version %d: error is %v, want a version error", test.messageType, test.version, err)
+		} else if versionErr.MinVersion != test.minVersion || versionErr.MaxVersion != managementMaxVersion {
+			t.Errorf("type %d version %d: versions are %d to %d, want %d to %d", test.messageType, test.version,
+				versionErr.MinVersion, versionErr.MaxVersion, test.minVersion, managementMaxVersion)
+		}
+
+		if test.minVersion != 0 {
+			docsisManagement.Lenient = true
+			if err := docsisManagement.DecodeFromBytes(data, nil); err != nil {
+				t.Errorf("type %d version %d: lenient decode failed: %v", test.messageType, test.version, err)
+			}
+		}
+	}
+}
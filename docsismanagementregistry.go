@@ -0,0 +1,76 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/google/gopacket"
+)
+
+// ManagementVersionAny registers a management message type for every version
+// that doesn't have a more specific registration.
+const ManagementVersionAny = 0
+
+// ManagementLayerFactory returns a new DecodingLayer for a management message type.
+type ManagementLayerFactory func() gopacket.DecodingLayer
+
+type managementTypeKey struct {
+	messageType uint8
+	version     uint8
+}
+
+type managementTypeEntry struct {
+	layerType gopacket.LayerType
+	factory   ManagementLayerFactory
+}
+
+var managementTypesMu sync.RWMutex
+var managementTypes = make(map[managementTypeKey]managementTypeEntry)
+
+// RegisterManagementType registers the layer type decoding a management message type and version.
+// A registration for a specific version takes precedence over ManagementVersionAny.
+// Registering the same type and version again replaces the previous registration,
+// so applications can override the built-in decoders.
+// The factory is optional and only used by ManagementDecodingLayers.
+func RegisterManagementType(messageType uint8, version uint8, layerType gopacket.LayerType, factory ManagementLayerFactory) {
+	managementTypesMu.Lock()
+	defer managementTypesMu.Unlock()
+
+	managementTypes[managementTypeKey{messageType: messageType, version: version}] = managementTypeEntry{
+		layerType: layerType,
+		factory:   factory,
+	}
+}
+
+// ManagementLayerType returns the layer type registered for a management message type and version.
+func ManagementLayerType(messageType uint8, version uint8) (gopacket.LayerType, bool) {
+	managementTypesMu.RLock()
+	defer managementTypesMu.RUnlock()
+
+	if entry, ok := managementTypes[managementTypeKey{messageType: messageType, version: version}]; ok {
+		return entry.layerType, true
+	}
+	if entry, ok := managementTypes[managementTypeKey{messageType: messageType, version: ManagementVersionAny}]; ok {
+		return entry.layerType, true
+	}
+
+	return gopacket.LayerTypePayload, false
+}
+
+// ManagementDecodingLayers returns new instances of all registered management decoders,
+// ready to be passed to gopacket.NewDecodingLayerParser.
+func ManagementDecodingLayers() []gopacket.DecodingLayer {
+	managementTypesMu.RLock()
+	defer managementTypesMu.RUnlock()
+
+	seen := make(map[gopacket.LayerType]bool)
+	var result []gopacket.DecodingLayer
+	for _, entry := range managementTypes {
+		if entry.factory == nil || seen[entry.layerType] {
+			continue
+		}
+		seen[entry.layerType] = true
+		result = append(result, entry.factory())
+	}
+
+	return result
+}
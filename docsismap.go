@@ -0,0 +1,233 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// LayerTypeDOCSISMap type registration
+var LayerTypeDOCSISMap = gopacket.RegisterLayerType(1013, gopacket.LayerTypeMetadata{Name: "DOCSIS Management Upstream Bandwidth Allocation", Decoder: gopacket.DecodeFunc(decodeDOCSISMap)})
+
+// LayerTypeDOCSISMap5 type registration
+var LayerTypeDOCSISMap5 = gopacket.RegisterLayerType(1043, gopacket.LayerTypeMetadata{Name: "DOCSIS Management Upstream Bandwidth Allocation Version 5", Decoder: gopacket.DecodeFunc(decodeDOCSISMap5)})
+
+func init() {
+	RegisterManagementType(DocsisManagementMap, ManagementVersionAny, LayerTypeDOCSISMap, func() gopacket.DecodingLayer { return &DOCSISMap{} })
+	RegisterManagementType(DocsisManagementMap, 5, LayerTypeDOCSISMap5, func() gopacket.DecodingLayer { return &DOCSISMap5{} })
+}
+
+// interval usage codes of MAP information elements
+const (
+	IUCRequest              = 1
+	IUCRequestData          = 2
+	IUCInitialMaintenance   = 3
+	IUCStationMaintenance   = 4
+	IUCShortDataGrant       = 5
+	IUCLongDataGrant        = 6
+	IUCNull                 = 7
+	IUCDataAck              = 8
+	IUCAdvPhyShortDataGrant = 9
+	IUCAdvPhyLongDataGrant  = 10
+	IUCAdvPhyUGS            = 11
+	IUCDataProfile12        = 12
+	IUCDataProfile13        = 13
+	IUCExpansion            = 15
+)
+
+// MapIE is a MAP information element.
+type MapIE struct {
+	SID    uint16
+	IUC    uint8
+	Offset uint16
+	// Minislots is the length of the interval up to the next element.
+	// It's only set for elements before the null IE.
+	Minislots uint16
+}
+
+// DOCSISBaseMap contains the fields shared by all MAP versions.
+type DOCSISBaseMap struct {
+	UpstreamChannelID uint8
+	UCDCount          uint8
+	NumberOfElements  uint16
+	Reserved          uint8
+	// Categories are the MAP IE category bits, only present in version 5 MAPs
+	Categories          uint8
+	AllocStartTime      uint32
+	AckTime             uint32
+	RangingBackoffStart uint8
+	RangingBackoffEnd   uint8
+	DataBackoffStart    uint8
+	DataBackoffEnd      uint8
+	Elements            []MapIE
+}
+
+// parseMap decodes the MAP and returns the end of the information elements.
+func (docsis *DOCSISBaseMap) parseMap(data []byte, version5 bool) (int, error) {
+	if len(data) < 16 {
+		return 0, fmt.Errorf("docsis map packet is too small for the header")
+	}
+
+	docsis.UpstreamChannelID = data[0]
+	docsis.UCDCount = data[1]
+	if version5 {
+		// 9 bit number of elements, 3 reserved bits and 4 category bits
+		docsis.NumberOfElements = binary.BigEndian.Uint16(data[2:4]) >> 7
+		docsis.Reserved = (data[3] >> 4) & 0x07
+		docsis.Categories = data[3] & 0x0f
+	} else {
+		docsis.NumberOfElements = uint16(data[2])
+		docsis.Reserved = data[3]
+		docsis.Categories = 0
+	}
+	docsis.AllocStartTime = binary.BigEndian.Uint32(data[4:8])
+	docsis.AckTime = binary.BigEndian.Uint32(data[8:12])
+	docsis.RangingBackoffStart = data[12]
+	docsis.RangingBackoffEnd = data[13]
+	docsis.DataBackoffStart = data[14]
+	docsis.DataBackoffEnd = data[15]
+	docsis.Elements = docsis.Elements[:0]
+
+	elementsEnd := 16 + 4*int(docsis.NumberOfElements)
+	if len(data) < elementsEnd {
+		return 0, fmt.Errorf("docsis map packet is too small for the information elements")
+	}
+
+	for i := 16; i < elementsEnd; i += 4 {
+		element := binary.BigEndian.Uint32(data[i : i+4])
+		ie := MapIE{
+			SID:    uint16(element >> 18),
+			IUC:    uint8(element>>14) & 0x0f,
+			Offset: uint16(element) & 0x3fff,
+		}
+		docsis.Elements = append(docsis.Elements, ie)
+
+		// the offset of an expansion IE is the number of additional 32 bit words
+		if ie.IUC == IUCExpansion {
+			i += 4 * int(ie.Offset)
+		}
+	}
+
+	// the intervals end at the offset of the following element, the null IE marks the end of the map.
+	// The offset of an expansion IE isn't a minislot offset, so these are skipped.
+	for i := 0; i < len(docsis.Elements); i++ {
+		if docsis.Elements[i].IUC == IUCNull {
+			break
+		}
+		if docsis.Elements[i].IUC == IUCExpansion {
+			continue
+		}
+
+		next := i + 1
+		for next < len(docsis.Elements) && docsis.Elements[next].IUC == IUCExpansion {
+			next++
+		}
+		if next < len(docsis.Elements) && docsis.Elements[next].Offset >= docsis.Elements[i].Offset {
+			docsis.Elements[i].Minislots = docsis.Elements[next].Offset - docsis.Elements[i].Offset
+		}
+	}
+
+	return elementsEnd, nil
+}
+
+// MapLength returns the number of minislots described by the map.
+func (docsis *DOCSISBaseMap) MapLength() uint16 {
+	for _, element := range docsis.Elements {
+		if element.IUC == IUCNull {
+			return element.Offset
+		}
+	}
+	return 0
+}
+
+// DOCSISMap is a DOCSIS Management MAP message.
+type DOCSISMap struct {
+	layers.BaseLayer
+	DOCSISBaseMap
+}
+
+// LayerType returns LayerTypeDOCSISMap
+func (docsis *DOCSISMap) LayerType() gopacket.LayerType {
+	return LayerTypeDOCSISMap
+}
+
+// DecodeFromBytes decodes the given bytes into this layer.
+func (docsis *DOCSISMap) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
+	elementsEnd, err := docsis.parseMap(data, false)
+	if err != nil {
+		return err
+	}
+
+	docsis.Contents = data[:elementsEnd]
+	docsis.Payload = data[elementsEnd:]
+
+	return nil
+}
+
+// CanDecode returns the set of layer types that this DecodingLayer can decode.
+func (docsis *DOCSISMap) CanDecode() gopacket.LayerClass {
+	return LayerTypeDOCSISMap
+}
+
+// NextLayerType returns the layer type contained by this DecodingLayer.
+func (docsis *DOCSISMap) NextLayerType() gopacket.LayerType {
+	return gopacket.LayerTypePayload
+}
+
+func decodeDOCSISMap(data []byte, p gopacket.PacketBuilder) error {
+	docsis := &DOCSISMap{}
+	err := docsis.DecodeFromBytes(data, p)
+	if err != nil {
+		return err
+	}
+	p.AddLayer(docsis)
+
+	return p.NextDecoder(docsis.NextLayerType())
+}
+
+// DOCSISMap5 is a DOCSIS Management MAP message for OFDMA channels (version 5).
+type DOCSISMap5 struct {
+	layers.BaseLayer
+	DOCSISBaseMap
+}
+
+// LayerType returns LayerTypeDOCSISMap5
+func (docsis *DOCSISMap5) LayerType() gopacket.LayerType {
+	return LayerTypeDOCSISMap5
+}
+
+// DecodeFromBytes decodes the given bytes into this layer.
+func (docsis *DOCSISMap5) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
+	elementsEnd, err := docsis.parseMap(data, true)
+	if err != nil {
+		return err
+	}
+
+	docsis.Contents = data[:elementsEnd]
+	docsis.Payload = data[elementsEnd:]
+
+	return nil
+}
+
+// CanDecode returns the set of layer types that this DecodingLayer can decode.
+func (docsis *DOCSISMap5) CanDecode() gopacket.LayerClass {
+	return LayerTypeDOCSISMap5
+}
+
+// NextLayerType returns the layer type contained by this DecodingLayer.
+func (docsis *DOCSISMap5) NextLayerType() gopacket.LayerType {
+	return gopacket.LayerTypePayload
+}
+
+func decodeDOCSISMap5(data []byte, p gopacket.PacketBuilder) error {
+	docsis := &DOCSISMap5{}
+	err := docsis.DecodeFromBytes(data, p)
+	if err != nil {
+		return err
+	}
+	p.AddLayer(docsis)
+
+	return p.NextDecoder(docsis.NextLayerType())
+}
@@ -0,0 +1,93 @@
+package main
+
+import "testing"
+
+func buildMapIE(sid uint16, iuc uint8, offset uint16) []byte {
+	element := uint32(sid)<<18 | uint32(iuc)<<14 | uint32(offset)
+	return []byte{byte(element >> 24), byte(element >> 16), byte(element >> 8), byte(element)}
+}
+
+func TestDOCSISMapAccounting(t *testing.T) {
+	data := []byte{0x04, 0x01, 0x04, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x02, 0x03, 0x04}
+	data = append(data, buildMapIE(0x3fff, IUCRequest, 0)...)
+	data = append(data, buildMapIE(0x12, IUCLongDataGrant, 10)...)
+	data = append(data, buildMapIE(0, IUCNull, 40)...)
+	data = append(data, buildMapIE(0x12, IUCDataAck, 0)...)
+
+	m := &DOCSISMap{}
+	if err := m.DecodeFromBytes(data, nil); err != nil {
+		t.Fatal(err)
+	}
+	if m.NumberOfElements != 4 || len(m.Elements) != 4 || m.MapLength() != 40 {
+		t.Fatalf("decoded %+v", m.DOCSISBaseMap)
+	}
+
+	accounting := newMapAccounting()
+	accounting.Add(&m.DOCSISBaseMap)
+	if usage := accounting.Usage()[0x12]; usage.Grants != 1 || usage.Minislots != 30 {
+		t.Errorf("usage of sid 0x12 is %+v, want 1 grant of 30 minislots", usage)
+	}
+
+	// the grant before an expansion IE ends at the following regular element
+	data = []byte{0x04, 0x02, 0x06, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x02, 0x03, 0x04}
+	data = append(data, buildMapIE(0x12, IUCShortDataGrant, 0)...)
+	data = append(data, buildMapIE(0x13, IUCLongDataGrant, 40)...)
+	data = append(data, buildMapIE(0x13, IUCExpansion, 1)...)
+	data = append(data, 0x00, 0x00, 0x00, 0x2a)
+	data = append(data, buildMapIE(0, IUCNull, 60)...)
+	data = append(data, buildMapIE(0x12, IUCDataAck, 0)...)
+
+	if err := m.DecodeFromBytes(data, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Elements) != 5 || m.Elements[1].Minislots != 20 || m.Elements[2].Minislots != 0 {
+		t.Fatalf("elements are %+v, want a grant of 20 minislots before the expansion IE", m.Elements)
+	}
+
+	accounting.Add(&m.DOCSISBaseMap)
+	if usage := accounting.Usage()[0x13]; usage.Grants != 1 || usage.Minislots != 20 {
+		t.Errorf("usage of sid 0x13 is %+v, want 1 grant of 20 minislots", usage)
+	}
+	if usage := accounting.Usage()[0x12]; usage.Grants != 2 || usage.Minislots != 70 {
+		t.Errorf("usage of sid 0x12 is %+v, want 2 grants of 70 minislots", usage)
+	}
+}
+
+func TestDOCSISMap5(t *testing.T) {
+	if layerType, _ := ManagementLayerType(DocsisManagementMap, 5); layerType != LayerTypeDOCSISMap5 {
+		t.Errorf("version 5 MAPs decode as %v", layerType)
+	}
+	if layerType, _ := ManagementLayerType(DocsisManagementMap, 3); layerType != LayerTypeDOCSISMap {
+		t.Errorf("version 3 MAPs decode as %v", layerType)
+	}
+
+	// 3 elements, reserved bits 0 and category bits 0b1010
+	data := []byte{0x06, 0x02, 0x01, 0x8a, 0x00, 0x00, 0x20, 0x00, 0x00, 0x00, 0x10, 0x00, 0x00, 0x03, 0x01, 0x05}
+	data = append(data, buildMapIE(0x21, IUCDataProfile12, 0)...)
+	data = append(data, buildMapIE(0x22, IUCDataProfile13, 8)...)
+	data = append(data, buildMapIE(0, IUCNull, 20)...)
+	data = append(data, 0xff)
+
+	m := &DOCSISMap5{}
+	if err := m.DecodeFromBytes(data, nil); err != nil {
+		t.Fatal(err)
+	}
+	if m.UpstreamChannelID != 6 || m.UCDCount != 2 || m.NumberOfElements != 3 || m.Reserved != 0 || m.Categories != 0x0a {
+		t.Errorf("decoded header %+v", m.DOCSISBaseMap)
+	}
+	if m.AllocStartTime != 0x2000 || m.AckTime != 0x1000 || m.DataBackoffStart != 1 || m.DataBackoffEnd != 5 {
+		t.Errorf("decoded times and backoff %+v", m.DOCSISBaseMap)
+	}
+	if len(m.Elements) != 3 || m.Elements[0].Minislots != 8 || m.Elements[1].Minislots != 12 || m.MapLength() != 20 {
+		t.Errorf("elements are %+v", m.Elements)
+	}
+	if len(m.Payload) != 1 {
+		t.Errorf("payload is %x, want the byte after the elements", m.Payload)
+	}
+
+	accounting := newMapAccounting()
+	accounting.Add(&m.DOCSISBaseMap)
+	if usage := accounting.Usage()[0x22]; usage.Minislots != 12 || usage.ByIUC[IUCDataProfile13] != 12 {
+		t.Errorf("usage of sid 0x22 is %+v, want 12 minislots of IUC 13", usage)
+	}
+}
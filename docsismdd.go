@@ -0,0 +1,307 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sort"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// LayerTypeDOCSISMDD type registration
+var LayerTypeDOCSISMDD = gopacket.RegisterLayerType(1017, gopacket.LayerTypeMetadata{Name: "DOCSIS Management MAC Domain Descriptor", Decoder: gopacket.DecodeFunc(decodeDOCSISMDD)})
+
+func init() {
+	RegisterManagementType(DocsisManagementMDD, ManagementVersionAny, LayerTypeDOCSISMDD, func() gopacket.DecodingLayer { return &DOCSISMDD{} })
+}
+
+var mddSchema = &TLVSchema{
+	LengthSize: 1,
+	Nested: map[uint8]*TLVSchema{
+		// downstream active channel list
+		1: nil,
+		// MAC domain downstream service group
+		2: nil,
+		// receive channel profile reporting control
+		4: nil,
+		// IP initialization parameters
+		5: nil,
+		// upstream active channel list
+		7: nil,
+		// CM-STATUS event control
+		11: nil,
+		// DSG DA-to-DSID association entry
+		13: nil,
+	},
+}
+
+// DownstreamActiveChannel is an entry of the MDD downstream active channel list.
+type DownstreamActiveChannel struct {
+	ChannelID uint8
+	// Frequency is the center frequency in Hz
+	Frequency uint32
+	// Modulation is 0 for 64-QAM and 1 for 256-QAM
+	Modulation uint8
+	// Annex is 0 for J.83 Annex A, 1 for Annex B and 2 for Annex C
+	Annex                    uint8
+	PrimaryCapable           uint8
+	CMStatusEventEnable      uint16
+	MapUCDTransportIndicator uint8
+	TLVs                     TLVs
+}
+
+// DownstreamServiceGroup is a MAC domain downstream service group of the MDD.
+type DownstreamServiceGroup struct {
+	ID         uint8
+	ChannelIDs []uint8
+}
+
+// UpstreamActiveChannel is an entry of the MDD upstream active channel list.
+type UpstreamActiveChannel struct {
+	ChannelID            uint8
+	CMStatusEventEnable  uint16
+	Priority             uint8
+	DownstreamChannelIDs []uint8
+	TLVs                 TLVs
+}
+
+// DOCSISMDD is a DOCSIS Management MAC Domain Descriptor.
+type DOCSISMDD struct {
+	layers.BaseLayer
+	ConfigChangeCount              uint8
+	NumberOfFragments              uint8
+	FragmentSequenceNumber         uint8
+	CurrentChannelDCID             uint8
+	DownstreamActiveChannels       []DownstreamActiveChannel
+	DownstreamServiceGroups        []DownstreamServiceGroup
+	DownstreamAmbiguityFrequencies []uint32
+	IPProvisioningMode             uint8
+	PreRegistrationDSID            uint32
+	EarlyAuthenticationEncryption  uint8
+	UpstreamActiveChannels         []UpstreamActiveChannel
+	UpstreamAmbiguityChannelIDs    []uint8
+	UpstreamFrequencyRange         uint8
+	SymbolClockLockingIndicator    uint8
+	ExtendedUpstreamPowerSupport   uint8
+	CMStatusEventEnableNonChannel  uint16
+	TLVs                           TLVs
+}
+
+// LayerType returns LayerTypeDOCSISMDD
+func (docsis *DOCSISMDD) LayerType() gopacket.LayerType {
+	return LayerTypeDOCSISMDD
+}
+
+// DecodeFromBytes decodes the given bytes into this layer.
+func (docsis *DOCSISMDD) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
+	if len(data) < 4 {
+		return fmt.Errorf("docsis mdd packet is too small for the header")
+	}
+
+	*docsis = DOCSISMDD{}
+	docsis.ConfigChangeCount = data[0]
+	docsis.NumberOfFragments = data[1]
+	docsis.FragmentSequenceNumber = data[2]
+	docsis.CurrentChannelDCID = data[3]
+
+	tlvs, err := DecodeTLVs(data[4:], mddSchema)
+	if err != nil {
+		return err
+	}
+
+	for _, tlv := range tlvs {
+		switch tlv.Type {
+		case 1:
+			var channel DownstreamActiveChannel
+			if channel, err = parseDownstreamActiveChannel(tlv); err == nil {
+				docsis.DownstreamActiveChannels = append(docsis.DownstreamActiveChannels, channel)
+			}
+		case 2:
+			group := DownstreamServiceGroup{}
+			for _, inner := range tlv.Children {
+				if inner.Type == 1 {
+					group.ID, err = inner.Uint8()
+				} else if inner.Type == 2 {
+					group.ChannelIDs = inner.Value
+				}
+				if err != nil {
+					break
+				}
+			}
+			docsis.DownstreamServiceGroups = append(docsis.DownstreamServiceGroups, group)
+		case 3:
+			if len(tlv.Value)%4 != 0 {
+				err = fmt.Errorf("tlv %d length isn't a multiple of 4", tlv.Type)
+				break
+			}
+			for i := 0; i < len(tlv.Value); i += 4 {
+				docsis.DownstreamAmbiguityFrequencies = append(docsis.DownstreamAmbiguityFrequencies, binary.BigEndian.Uint32(tlv.Value[i:i+4]))
+			}
+		case 5:
+			for _, inner := range tlv.Children {
+				if inner.Type == 1 {
+					docsis.IPProvisioningMode, err = inner.Uint8()
+				} else if inner.Type == 2 && len(inner.Value) == 3 {
+					docsis.PreRegistrationDSID = uint32(inner.Value[0])<<16 | uint32(binary.BigEndian.Uint16(inner.Value[1:3]))
+				}
+				if err != nil {
+					break
+				}
+			}
+		case 6:
+			docsis.EarlyAuthenticationEncryption, err = tlv.Uint8()
+		case 7:
+			channel := UpstreamActiveChannel{TLVs: tlv.Children}
+			for _, inner := range tlv.Children {
+				if inner.Type == 1 {
+					channel.ChannelID, err = inner.Uint8()
+				} else if inner.Type == 2 {
+					channel.CMStatusEventEnable, err = inner.Uint16()
+				} else if inner.Type == 3 {
+					channel.Priority, err = inner.Uint8()
+				} else if inner.Type == 4 {
+					channel.DownstreamChannelIDs = inner.Value
+				}
+				if err != nil {
+					break
+				}
+			}
+			docsis.UpstreamActiveChannels = append(docsis.UpstreamActiveChannels, channel)
+		case 8:
+			docsis.UpstreamAmbiguityChannelIDs = tlv.Value
+		case 9:
+			docsis.UpstreamFrequencyRange, err = tlv.Uint8()
+		case 10:
+			docsis.SymbolClockLockingIndicator, err = tlv.Uint8()
+		case 15:
+			docsis.CMStatusEventEnableNonChannel, err = tlv.Uint16()
+		case 16:
+			docsis.ExtendedUpstreamPowerSupport, err = tlv.Uint8()
+		}
+
+		if err != nil {
+			return fmt.Errorf("docsis mdd: %w", err)
+		}
+	}
+
+	docsis.TLVs = tlvs
+	docsis.Contents = data[:]
+	docsis.Payload = data[:0]
+
+	return nil
+}
+
+func parseDownstreamActiveChannel(tlv TLV) (DownstreamActiveChannel, error) {
+	channel := DownstreamActiveChannel{TLVs: tlv.Children}
+
+	var err error
+	for _, inner := range tlv.Children {
+		switch inner.Type {
+		case 1:
+			channel.ChannelID, err = inner.Uint8()
+		case 2:
+			channel.Frequency, err = inner.Uint32()
+		case 3:
+			var modulation uint8
+			modulation, err = inner.Uint8()
+			channel.Modulation = modulation & 0x0f
+			channel.Annex = (modulation & 0xf0) >> 4
+		case 4:
+			channel.PrimaryCapable, err = inner.Uint8()
+		case 5:
+			channel.CMStatusEventEnable, err = inner.Uint16()
+		case 6:
+			channel.MapUCDTransportIndicator, err = inner.Uint8()
+		}
+
+		if err != nil {
+			return channel, fmt.Errorf("downstream active channel: %w", err)
+		}
+	}
+
+	return channel, nil
+}
+
+// CanDecode returns the set of layer types that this DecodingLayer can decode.
+func (docsis *DOCSISMDD) CanDecode() gopacket.LayerClass {
+	return LayerTypeDOCSISMDD
+}
+
+// NextLayerType returns the layer type contained by this DecodingLayer.
+func (docsis *DOCSISMDD) NextLayerType() gopacket.LayerType {
+	return gopacket.LayerTypePayload
+}
+
+func decodeDOCSISMDD(data []byte, p gopacket.PacketBuilder) error {
+	docsis := &DOCSISMDD{}
+	err := docsis.DecodeFromBytes(data, p)
+	if err != nil {
+		return err
+	}
+	p.AddLayer(docsis)
+
+	return p.NextDecoder(docsis.NextLayerType())
+}
+
+type mddKey struct {
+	cmts               string
+	currentChannelDCID uint8
+}
+
+type mddFragments struct {
+	configChangeCount uint8
+	numberOfFragments uint8
+	fragments         map[uint8][]byte
+}
+
+// MDDAssembler combines fragmented MDD messages.
+type MDDAssembler struct {
+	pending map[mddKey]*mddFragments
+}
+
+func newMDDAssembler() *MDDAssembler {
+	return &MDDAssembler{pending: make(map[mddKey]*mddFragments)}
+}
+
+// Add collects a MDD fragment sent by the CMTS.
+// Once all fragments are complete it returns the MDD decoded from all of them.
+func (assembler *MDDAssembler) Add(cmts net.HardwareAddr, mdd *DOCSISMDD) (*DOCSISMDD, error) {
+	key := mddKey{cmts: cmts.String(), currentChannelDCID: mdd.CurrentChannelDCID}
+
+	fragments, ok := assembler.pending[key]
+	if !ok || fragments.configChangeCount != mdd.ConfigChangeCount || fragments.numberOfFragments != mdd.NumberOfFragments {
+		fragments = &mddFragments{
+			configChangeCount: mdd.ConfigChangeCount,
+			numberOfFragments: mdd.NumberOfFragments,
+			fragments:         make(map[uint8][]byte),
+		}
+		assembler.pending[key] = fragments
+	}
+
+	// the fragments are split at TLV boundaries so the TLVs can be concatenated
+	fragments.fragments[mdd.FragmentSequenceNumber] = append([]byte(nil), mdd.Contents[4:]...)
+	if len(fragments.fragments) < int(fragments.numberOfFragments) {
+		return nil, nil
+	}
+	delete(assembler.pending, key)
+
+	sequenceNumbers := make([]int, 0, len(fragments.fragments))
+	for sequenceNumber := range fragments.fragments {
+		sequenceNumbers = append(sequenceNumbers, int(sequenceNumber))
+	}
+	sort.Ints(sequenceNumbers)
+
+	data := []byte{mdd.ConfigChangeCount, 1, 1, mdd.CurrentChannelDCID}
+	for _, sequenceNumber := range sequenceNumbers {
+		data = append(data, fragments.fragments[uint8(sequenceNumber)]...)
+	}
+
+	combined := &DOCSISMDD{}
+	if err := combined.DecodeFromBytes(data, gopacket.NilDecodeFeedback); err != nil {
+		return nil, err
+	}
+
+	return combined, nil
+}
@@ -0,0 +1,388 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// LayerTypeDOCSISOCD type registration
+var LayerTypeDOCSISOCD = gopacket.RegisterLayerType(1037, gopacket.LayerTypeMetadata{Name: "DOCSIS Management OFDM Channel Descriptor", Decoder: gopacket.DecodeFunc(decodeDOCSISOCD)})
+
+// LayerTypeDOCSISDPD type registration
+var LayerTypeDOCSISDPD = gopacket.RegisterLayerType(1038, gopacket.LayerTypeMetadata{Name: "DOCSIS Management Downstream Profile Descriptor", Decoder: gopacket.DecodeFunc(decodeDOCSISDPD)})
+
+func init() {
+	RegisterManagementType(DocsisManagementOCD, ManagementVersionAny, LayerTypeDOCSISOCD, func() gopacket.DecodingLayer { return &DOCSISOCD{} })
+	RegisterManagementType(DocsisManagementDPD, ManagementVersionAny, LayerTypeDOCSISDPD, func() gopacket.DecodingLayer { return &DOCSISDPD{} })
+}
+
+// Subcarrier assignment types
+const (
+	SubcarrierAssignmentRange       = 0
+	SubcarrierAssignmentRangeSkip   = 1
+	SubcarrierAssignmentList        = 2
+	subcarrierAssignmentTypeInvalid = 3
+)
+
+// Subcarrier usages of the OCD subcarrier assignments
+const (
+	SubcarrierUsageContinuousPilots = 1
+	SubcarrierUsageExcluded         = 16
+	SubcarrierUsagePLC              = 20
+)
+
+// Subcarrier modulations of the DPD subcarrier assignments
+const (
+	OFDMModulationZeroBitLoaded   = 0
+	OFDMModulationContinuousPilot = 1
+	OFDMModulationQPSK            = 2
+	OFDMModulation16QAM           = 4
+	OFDMModulation64QAM           = 5
+	OFDMModulation128QAM          = 6
+	OFDMModulation256QAM          = 7
+	OFDMModulation512QAM          = 8
+	OFDMModulation1024QAM         = 9
+	OFDMModulation2048QAM         = 10
+	OFDMModulation4096QAM         = 11
+	OFDMModulation8192QAM         = 12
+	OFDMModulation16384QAM        = 13
+)
+
+var ocdCyclicPrefixSamples = []int{192, 256, 512, 768, 1024}
+var ocdRollOffSamples = []int{0, 64, 128, 192, 256}
+
+// SubcarrierAssignment assigns a usage (OCD) or modulation (DPD) to a range or list of subcarriers.
+type SubcarrierAssignment struct {
+	Type uint8
+	// Value is the subcarrier usage in an OCD and the modulation in a DPD
+	Value uint8
+	// Start and End are set for the range types
+	Start uint16
+	End   uint16
+	// List is set for the list type
+	List []uint16
+}
+
+// Subcarriers returns the indexes of all subcarriers in the assignment.
+func (assignment SubcarrierAssignment) Subcarriers() []uint16 {
+	if assignment.Type == SubcarrierAssignmentList {
+		return assignment.List
+	}
+
+	step := 1
+	if assignment.Type == SubcarrierAssignmentRangeSkip {
+		step = 2
+	}
+
+	var result []uint16
+	for i := int(assignment.Start); i <= int(assignment.End); i += step {
+		result = append(result, uint16(i))
+	}
+	return result
+}
+
+func parseSubcarrierAssignment(value []byte, valueMask byte) (SubcarrierAssignment, error) {
+	if len(value) < 1 {
+		return SubcarrierAssignment{}, fmt.Errorf("subcarrier assignment is empty")
+	}
+
+	assignment := SubcarrierAssignment{
+		Type:  (value[0] & 0xc0) >> 6, // 0b11000000
+		Value: value[0] & valueMask,
+	}
+
+	switch assignment.Type {
+	case SubcarrierAssignmentRange, SubcarrierAssignmentRangeSkip:
+		if len(value) != 5 {
+			return assignment, fmt.Errorf("subcarrier assignment range has length %d instead of 5", len(value))
+		}
+		assignment.Start = binary.BigEndian.Uint16(value[1:3])
+		assignment.End = binary.BigEndian.Uint16(value[3:5])
+	case SubcarrierAssignmentList:
+		if (len(value)-1)%2 != 0 {
+			return assignment, fmt.Errorf("subcarrier assignment list length isn't a multiple of 2")
+		}
+		for i := 1; i < len(value); i += 2 {
+			assignment.List = append(assignment.List, binary.BigEndian.Uint16(value[i:i+2]))
+		}
+	case subcarrierAssignmentTypeInvalid:
+		return assignment, fmt.Errorf("subcarrier assignment type %d is reserved", assignment.Type)
+	}
+
+	return assignment, nil
+}
+
+// DOCSISOCD is a DOCSIS Management OFDM Channel Descriptor.
+type DOCSISOCD struct {
+	layers.BaseLayer
+	DownstreamChannelID uint8
+	ConfigChangeCount   uint8
+	// DFTSize is 0 for 4096 subcarriers with 50 kHz spacing and 1 for 8192 subcarriers with 25 kHz spacing
+	DFTSize uint8
+	// CyclicPrefix and RollOffPeriod are the encoded values, see CyclicPrefixSamples and RollOffSamples
+	CyclicPrefix  uint8
+	RollOffPeriod uint8
+	// SubcarrierZeroFrequency is the center frequency of subcarrier 0 in Hz
+	SubcarrierZeroFrequency uint32
+	TimeInterleavingDepth   uint8
+	SubcarrierAssignments   []SubcarrierAssignment
+	PrimaryCapable          uint8
+	TLVs                    TLVs
+}
+
+// LayerType returns LayerTypeDOCSISOCD
+func (docsis *DOCSISOCD) LayerType() gopacket.LayerType {
+	return LayerTypeDOCSISOCD
+}
+
+// DecodeFromBytes decodes the given bytes into this layer.
+func (docsis *DOCSISOCD) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
+	if len(data) < 2 {
+		return fmt.Errorf("docsis ocd packet is too small for the header")
+	}
+
+	*docsis = DOCSISOCD{}
+	docsis.DownstreamChannelID = data[0]
+	docsis.ConfigChangeCount = data[1]
+
+	tlvs, err := DecodeTLVs(data[2:], nil)
+	if err != nil {
+		return err
+	}
+
+	for _, tlv := range tlvs {
+		switch tlv.Type {
+		case 0:
+			docsis.DFTSize, err = tlv.Uint8()
+		case 1:
+			docsis.CyclicPrefix, err = tlv.Uint8()
+		case 2:
+			docsis.RollOffPeriod, err = tlv.Uint8()
+		case 3:
+			docsis.SubcarrierZeroFrequency, err = tlv.Uint32()
+		case 4:
+			docsis.TimeInterleavingDepth, err = tlv.Uint8()
+		case 5:
+			var assignment SubcarrierAssignment
+			if assignment, err = parseSubcarrierAssignment(tlv.Value, 0x1f); err == nil {
+				docsis.SubcarrierAssignments = append(docsis.SubcarrierAssignments, assignment)
+			}
+		case 6:
+			docsis.PrimaryCapable, err = tlv.Uint8()
+		}
+
+		if err != nil {
+			return fmt.Errorf("docsis ocd: %w", err)
+		}
+	}
+
+	docsis.TLVs = tlvs
+	docsis.Contents = data[:]
+	docsis.Payload = data[:0]
+
+	return nil
+}
+
+// SubcarrierSpacing returns the spacing of the subcarriers in Hz.
+func (docsis *DOCSISOCD) SubcarrierSpacing() uint32 {
+	if docsis.DFTSize == 1 {
+		return 25000
+	}
+	return 50000
+}
+
+// CyclicPrefixSamples returns the length of the cyclic prefix in samples or 0 if it's unknown.
+func (docsis *DOCSISOCD) CyclicPrefixSamples() int {
+	if int(docsis.CyclicPrefix) >= len(ocdCyclicPrefixSamples) {
+		return 0
+	}
+	return ocdCyclicPrefixSamples[docsis.CyclicPrefix]
+}
+
+// RollOffSamples returns the length of the roll-off period in samples or -1 if it's unknown.
+func (docsis *DOCSISOCD) RollOffSamples() int {
+	if int(docsis.RollOffPeriod) >= len(ocdRollOffSamples) {
+		return -1
+	}
+	return ocdRollOffSamples[docsis.RollOffPeriod]
+}
+
+// SubcarrierFrequency returns the center frequency of a subcarrier in Hz.
+func (docsis *DOCSISOCD) SubcarrierFrequency(subcarrier uint16) uint64 {
+	return uint64(docsis.SubcarrierZeroFrequency) + uint64(subcarrier)*uint64(docsis.SubcarrierSpacing())
+}
+
+// AssignmentsByUsage returns the subcarrier assignments with the given usage.
+func (docsis *DOCSISOCD) AssignmentsByUsage(usage uint8) []SubcarrierAssignment {
+	var result []SubcarrierAssignment
+	for _, assignment := range docsis.SubcarrierAssignments {
+		if assignment.Value == usage {
+			result = append(result, assignment)
+		}
+	}
+	return result
+}
+
+// ExcludedSubcarriers returns the subcarrier ranges that are excluded from the OFDM channel.
+func (docsis *DOCSISOCD) ExcludedSubcarriers() []SubcarrierAssignment {
+	return docsis.AssignmentsByUsage(SubcarrierUsageExcluded)
+}
+
+// PLCLocation returns the lowest subcarrier of the PHY link channel.
+func (docsis *DOCSISOCD) PLCLocation() (uint16, bool) {
+	for _, assignment := range docsis.AssignmentsByUsage(SubcarrierUsagePLC) {
+		if subcarriers := assignment.Subcarriers(); len(subcarriers) > 0 {
+			return subcarriers[0], true
+		}
+	}
+	return 0, false
+}
+
+// CanDecode returns the set of layer types that this DecodingLayer can decode.
+func (docsis *DOCSISOCD) CanDecode() gopacket.LayerClass {
+	return LayerTypeDOCSISOCD
+}
+
+// NextLayerType returns the layer type contained by this DecodingLayer.
+func (docsis *DOCSISOCD) NextLayerType() gopacket.LayerType {
+	return gopacket.LayerTypePayload
+}
+
+func decodeDOCSISOCD(data []byte, p gopacket.PacketBuilder) error {
+	docsis := &DOCSISOCD{}
+	err := docsis.DecodeFromBytes(data, p)
+	if err != nil {
+		return err
+	}
+	p.AddLayer(docsis)
+
+	return p.NextDecoder(docsis.NextLayerType())
+}
+
+// SubcarrierAssignmentVector assigns a modulation to each of a consecutive run of subcarriers.
+type SubcarrierAssignmentVector struct {
+	Start       uint16
+	Modulations []uint8
+}
+
+func parseSubcarrierAssignmentVector(value []byte) (SubcarrierAssignmentVector, error) {
+	if len(value) < 2 {
+		return SubcarrierAssignmentVector{}, fmt.Errorf("subcarrier assignment vector is too small")
+	}
+
+	header := binary.BigEndian.Uint16(value[0:2])
+	odd := (header & 0x8000) == 0x8000
+	vector := SubcarrierAssignmentVector{Start: header & 0x3fff}
+
+	// every byte holds the modulation of two subcarriers
+	for _, b := range value[2:] {
+		vector.Modulations = append(vector.Modulations, (b&0xf0)>>4, b&0x0f)
+	}
+	if odd && len(vector.Modulations) > 0 {
+		vector.Modulations = vector.Modulations[:len(vector.Modulations)-1]
+	}
+
+	return vector, nil
+}
+
+// dpdSchema describes the DPD TLVs, the subcarrier assignment vector uses a 2 byte length.
+var dpdSchema = &TLVSchema{LengthSize: 1, LengthSizes: map[uint8]int{6: 2}}
+
+// DOCSISDPD is a DOCSIS Management Downstream Profile Descriptor.
+type DOCSISDPD struct {
+	layers.BaseLayer
+	DownstreamChannelID   uint8
+	ProfileID             uint8
+	ConfigChangeCount     uint8
+	SubcarrierAssignments []SubcarrierAssignment
+	Vectors               []SubcarrierAssignmentVector
+	TLVs                  TLVs
+}
+
+// LayerType returns LayerTypeDOCSISDPD
+func (docsis *DOCSISDPD) LayerType() gopacket.LayerType {
+	return LayerTypeDOCSISDPD
+}
+
+// DecodeFromBytes decodes the given bytes into this layer.
+func (docsis *DOCSISDPD) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
+	if len(data) < 3 {
+		return fmt.Errorf("docsis dpd packet is too small for the header")
+	}
+
+	*docsis = DOCSISDPD{}
+	docsis.DownstreamChannelID = data[0]
+	docsis.ProfileID = data[1]
+	docsis.ConfigChangeCount = data[2]
+
+	tlvs, err := DecodeTLVs(data[3:], dpdSchema)
+	if err != nil {
+		return err
+	}
+
+	for _, tlv := range tlvs {
+		switch tlv.Type {
+		case 5:
+			var assignment SubcarrierAssignment
+			if assignment, err = parseSubcarrierAssignment(tlv.Value, 0x0f); err == nil {
+				docsis.SubcarrierAssignments = append(docsis.SubcarrierAssignments, assignment)
+			}
+		case 6:
+			var vector SubcarrierAssignmentVector
+			if vector, err = parseSubcarrierAssignmentVector(tlv.Value); err == nil {
+				docsis.Vectors = append(docsis.Vectors, vector)
+			}
+		}
+
+		if err != nil {
+			return fmt.Errorf("docsis dpd: %w", err)
+		}
+	}
+
+	docsis.TLVs = tlvs
+	docsis.Contents = data[:]
+	docsis.Payload = data[:0]
+
+	return nil
+}
+
+// ModulationMap returns the modulation of every subcarrier described by the profile.
+// Vectors are applied after the ranges and lists so they take precedence.
+func (docsis *DOCSISDPD) ModulationMap() map[uint16]uint8 {
+	result := make(map[uint16]uint8)
+	for _, assignment := range docsis.SubcarrierAssignments {
+		for _, subcarrier := range assignment.Subcarriers() {
+			result[subcarrier] = assignment.Value
+		}
+	}
+	for _, vector := range docsis.Vectors {
+		for i, modulation := range vector.Modulations {
+			result[vector.Start+uint16(i)] = modulation
+		}
+	}
+	return result
+}
+
+// CanDecode returns the set of layer types that this DecodingLayer can decode.
+func (docsis *DOCSISDPD) CanDecode() gopacket.LayerClass {
+	return LayerTypeDOCSISDPD
+}
+
+// NextLayerType returns the layer type contained by this DecodingLayer.
+func (docsis *DOCSISDPD) NextLayerType() gopacket.LayerType {
+	return gopacket.LayerTypePayload
+}
+
+func decodeDOCSISDPD(data []byte, p gopacket.PacketBuilder) error {
+	docsis := &DOCSISDPD{}
+	err := docsis.DecodeFromBytes(data, p)
+	if err != nil {
+		return err
+	}
+	p.AddLayer(docsis)
+
+	return p.NextDecoder(docsis.NextLayerType())
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDOCSISDPDSubcarrierAssignmentVector(t *testing.T) {
+	data := []byte{0x01, 0x02, 0x03} // downstream channel, profile, change count
+	data = append(data,
+		0x06, 0x00, 0x04, 0x80, 0x0a, 0x12, 0x30, // odd vector starting at subcarrier 10 with a 2 byte length
+		0x07, 0x01, 0xff)
+
+	dpd := &DOCSISDPD{}
+	if err := dpd.DecodeFromBytes(data, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(dpd.Vectors) != 1 || dpd.Vectors[0].Start != 10 || !bytes.Equal(dpd.Vectors[0].Modulations, []byte{1, 2, 3}) {
+		t.Errorf("vectors are %+v", dpd.Vectors)
+	}
+	if len(dpd.TLVs) != 2 || dpd.TLVs[1].Type != 7 {
+		t.Errorf("tlvs are %+v", dpd.TLVs)
+	}
+
+	encoded, err := dpd.TLVs.Encode(dpdSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(encoded, data[3:]) {
+		t.Errorf("encoded tlvs are %x, want %x", encoded, data[3:])
+	}
+
+	// the 2 byte length of the vector has to be complete
+	if err := dpd.DecodeFromBytes([]byte{0x01, 0x02, 0x03, 0x06, 0x00}, nil); err == nil {
+		t.Error("truncated vector length decoded without error")
+	}
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// LayerTypeDOCSISRngReq type registration
+var LayerTypeDOCSISRngReq = gopacket.RegisterLayerType(1014, gopacket.LayerTypeMetadata{Name: "DOCSIS Management Ranging Request", Decoder: gopacket.DecodeFunc(decodeDOCSISRngReq)})
+
+// LayerTypeDOCSISRngRsp type registration
+var LayerTypeDOCSISRngRsp = gopacket.RegisterLayerType(1015, gopacket.LayerTypeMetadata{Name: "DOCSIS Management Ranging Response", Decoder: gopacket.DecodeFunc(decodeDOCSISRngRsp)})
+
+// LayerTypeDOCSISBInitRngReq type registration
+var LayerTypeDOCSISBInitRngReq = gopacket.RegisterLayerType(1016, gopacket.LayerTypeMetadata{Name: "DOCSIS Management Bonded Initial Ranging Request", Decoder: gopacket.DecodeFunc(decodeDOCSISBInitRngReq)})
+
+func init() {
+	RegisterManagementType(DocsisManagementRngReq, ManagementVersionAny, LayerTypeDOCSISRngReq, func() gopacket.DecodingLayer { return &DOCSISRngReq{} })
+	RegisterManagementType(DocsisManagementRngRsp, ManagementVersionAny, LayerTypeDOCSISRngRsp, func() gopacket.DecodingLayer { return &DOCSISRngRsp{} })
+	RegisterManagementType(DocsisManagementBInitRngReq, ManagementVersionAny, LayerTypeDOCSISBInitRngReq, func() gopacket.DecodingLayer { return &DOCSISBInitRngReq{} })
+}
+
+// ranging status values of the RNG-RSP
+const (
+	RangingStatusContinue = 1
+	RangingStatusAbort    = 2
+	RangingStatusSuccess  = 3
+)
+
+// DOCSISRngReq is a DOCSIS Management Ranging Request.
+type DOCSISRngReq struct {
+	layers.BaseLayer
+	Sid                 uint16
+	DownstreamChannelID uint8
+	PendingTillComplete uint8
+}
+
+// LayerType returns LayerTypeDOCSISRngReq
+func (docsis *DOCSISRngReq) LayerType() gopacket.LayerType {
+	return LayerTypeDOCSISRngReq
+}
+
+// DecodeFromBytes decodes the given bytes into this layer.
+func (docsis *DOCSISRngReq) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
+	if len(data) < 4 {
+		return fmt.Errorf("docsis rng req packet is too small for the header")
+	}
+
+	docsis.Sid = binary.BigEndian.Uint16(data[0:2])
+	docsis.DownstreamChannelID = data[2]
+	docsis.PendingTillComplete = data[3]
+
+	docsis.Contents = data[:4]
+	docsis.Payload = data[4:]
+
+	return nil
+}
+
+// CanDecode returns the set of layer types that this DecodingLayer can decode.
+func (docsis *DOCSISRngReq) CanDecode() gopacket.LayerClass {
+	return LayerTypeDOCSISRngReq
+}
+
+// NextLayerType returns the layer type contained by this DecodingLayer.
+func (docsis *DOCSISRngReq) NextLayerType() gopacket.LayerType {
+	return gopacket.LayerTypePayload
+}
+
+func decodeDOCSISRngReq(data []byte, p gopacket.PacketBuilder) error {
+	docsis := &DOCSISRngReq{}
+	err := docsis.DecodeFromBytes(data, p)
+	if err != nil {
+		return err
+	}
+	p.AddLayer(docsis)
+
+	return p.NextDecoder(docsis.NextLayerType())
+}
+
+// DOCSISBInitRngReq is a DOCSIS Management Bonded Initial Ranging Request.
+type DOCSISBInitRngReq struct {
+	layers.BaseLayer
+	Sid                 uint16
+	CapabilityFlags     uint8
+	MDDSSGID            uint8
+	DownstreamChannelID uint8
+}
+
+// LayerType returns LayerTypeDOCSISBInitRngReq
+func (docsis *DOCSISBInitRngReq) LayerType() gopacket.LayerType {
+	return LayerTypeDOCSISBInitRngReq
+}
+
+// DecodeFromBytes decodes the given bytes into this layer.
+func (docsis *DOCSISBInitRngReq) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
+	if len(data) < 5 {
+		return fmt.Errorf("docsis b-init-rng req packet is too small for the header")
+	}
+
+	docsis.Sid = binary.BigEndian.Uint16(data[0:2])
+	docsis.CapabilityFlags = data[2]
+	docsis.MDDSSGID = data[3]
+	docsis.DownstreamChannelID = data[4]
+
+	docsis.Contents = data[:5]
+	docsis.Payload = data[5:]
+
+	return nil
+}
+
+// CanDecode returns the set of layer types that this DecodingLayer can decode.
+func (docsis *DOCSISBInitRngReq) CanDecode() gopacket.LayerClass {
+	return LayerTypeDOCSISBInitRngReq
+}
+
+// NextLayerType returns the layer type contained by this DecodingLayer.
+func (docsis *DOCSISBInitRngReq) NextLayerType() gopacket.LayerType {
+	return gopacket.LayerTypePayload
+}
+
+func decodeDOCSISBInitRngReq(data []byte, p gopacket.PacketBuilder) error {
+	docsis := &DOCSISBInitRngReq{}
+	err := docsis.DecodeFromBytes(data, p)
+	if err != nil {
+		return err
+	}
+	p.AddLayer(docsis)
+
+	return p.NextDecoder(docsis.NextLayerType())
+}
+
+// DOCSISRngRsp is a DOCSIS Management Ranging Response.
+type DOCSISRngRsp struct {
+	layers.BaseLayer
+	Sid               uint16
+	UpstreamChannelID uint8
+	// TimingAdjust in units of 1/64 of 6.25 microseconds
+	TimingAdjust int32
+	// PowerLevelAdjust in units of 0.25 dB
+	PowerLevelAdjust int8
+	// OffsetFrequencyAdjust in Hz
+	OffsetFrequencyAdjust       int16
+	TransmitEqualizationAdjust  []byte
+	RangingStatus               uint8
+	DownstreamFrequencyOverride uint32
+	UpstreamChannelIDOverride   uint8
+	TimingAdjustFractional      uint8
+	TransmitEqualizationSet     []byte
+	TLVs                        TLVs
+}
+
+// LayerType returns LayerTypeDOCSISRngRsp
+func (docsis *DOCSISRngRsp) LayerType() gopacket.LayerType {
+	return LayerTypeDOCSISRngRsp
+}
+
+// DecodeFromBytes decodes the given bytes into this layer.
+func (docsis *DOCSISRngRsp) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
+	if len(data) < 3 {
+		return fmt.Errorf("docsis rng rsp packet is too small for the header")
+	}
+
+	*docsis = DOCSISRngRsp{}
+	docsis.Sid = binary.BigEndian.Uint16(data[0:2])
+	docsis.UpstreamChannelID = data[2]
+
+	tlvs, err := DecodeTLVs(data[3:], nil)
+	if err != nil {
+		return err
+	}
+
+	for _, tlv := range tlvs {
+		switch tlv.Type {
+		case 1:
+			var timingAdjust uint32
+			timingAdjust, err = tlv.Uint32()
+			docsis.TimingAdjust = int32(timingAdjust)
+		case 2:
+			var powerLevelAdjust uint8
+			powerLevelAdjust, err = tlv.Uint8()
+			docsis.PowerLevelAdjust = int8(powerLevelAdjust)
+		case 3:
+			var offsetFrequencyAdjust uint16
+			offsetFrequencyAdjust, err = tlv.Uint16()
+			docsis.OffsetFrequencyAdjust = int16(offsetFrequencyAdjust)
+		case 4:
+			docsis.TransmitEqualizationAdjust = tlv.Value
+		case 5:
+			docsis.RangingStatus, err = tlv.Uint8()
+		case 6:
+			docsis.DownstreamFrequencyOverride, err = tlv.Uint32()
+		case 7:
+			docsis.UpstreamChannelIDOverride, err = tlv.Uint8()
+		case 8:
+			docsis.TimingAdjustFractional, err = tlv.Uint8()
+		case 9:
+			docsis.TransmitEqualizationSet = tlv.Value
+		}
+
+		if err != nil {
+			return fmt.Errorf("docsis rng rsp: %w", err)
+		}
+	}
+
+	docsis.TLVs = tlvs
+	docsis.Contents = data[:]
+	docsis.Payload = data[:0]
+
+	return nil
+}
+
+// CanDecode returns the set of layer types that this DecodingLayer can decode.
+func (docsis *DOCSISRngRsp) CanDecode() gopacket.LayerClass {
+	return LayerTypeDOCSISRngRsp
+}
+
+// NextLayerType returns the layer type contained by this DecodingLayer.
+func (docsis *DOCSISRngRsp) NextLayerType() gopacket.LayerType {
+	return gopacket.LayerTypePayload
+}
+
+func decodeDOCSISRngRsp(data []byte, p gopacket.PacketBuilder) error {
+	docsis := &DOCSISRngRsp{}
+	err := docsis.DecodeFromBytes(data, p)
+	if err != nil {
+		return err
+	}
+	p.AddLayer(docsis)
+
+	return p.NextDecoder(docsis.NextLayerType())
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// LayerTypeDOCSISRegReq type registration
+var LayerTypeDOCSISRegReq = gopacket.RegisterLayerType(1018, gopacket.LayerTypeMetadata{Name: "DOCSIS Management Registration Request", Decoder: gopacket.DecodeFunc(decodeDOCSISRegReq)})
+
+// LayerTypeDOCSISRegReqMp type registration
+var LayerTypeDOCSISRegReqMp = gopacket.RegisterLayerType(1019, gopacket.LayerTypeMetadata{Name: "DOCSIS Management Multipart Registration Request", Decoder: gopacket.DecodeFunc(decodeDOCSISRegReqMp)})
+
+// LayerTypeDOCSISRegAck type registration
+var LayerTypeDOCSISRegAck = gopacket.RegisterLayerType(1020, gopacket.LayerTypeMetadata{Name: "DOCSIS Management Registration Acknowledge", Decoder: gopacket.DecodeFunc(decodeDOCSISRegAck)})
+
+func init() {
+	RegisterManagementType(DocsisManagementRegReq, ManagementVersionAny, LayerTypeDOCSISRegReq, func() gopacket.DecodingLayer { return &DOCSISRegReq{} })
+	RegisterManagementType(DocsisManagementRegAck, ManagementVersionAny, LayerTypeDOCSISRegAck, func() gopacket.DecodingLayer { return &DOCSISRegAck{} })
+	RegisterManagementType(DocsisManagementRegReqMp, ManagementVersionAny, LayerTypeDOCSISRegReqMp, func() gopacket.DecodingLayer { return &DOCSISRegReqMp{} })
+}
+
+// DOCSISRegReq is a DOCSIS Management Registration Request.
+type DOCSISRegReq struct {
+	layers.BaseLayer
+	Sid uint16
+	DOCSISRegEncodings
+}
+
+// LayerType returns LayerTypeDOCSISRegReq
+func (docsis *DOCSISRegReq) LayerType() gopacket.LayerType {
+	return LayerTypeDOCSISRegReq
+}
+
+// DecodeFromBytes decodes the given bytes into this layer.
+func (docsis *DOCSISRegReq) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
+	if len(data) < 2 {
+		return fmt.Errorf("docsis reg req packet is too small for the header")
+	}
+
+	docsis.Sid = binary.BigEndian.Uint16(data[0:2])
+
+	if err := docsis.parseTLV(data[2:]); err != nil {
+		return err
+	}
+
+	docsis.Contents = data[:]
+	docsis.Payload = data[:0]
+
+	return nil
+}
+
+// CanDecode returns the set of layer types that this DecodingLayer can decode.
+func (docsis *DOCSISRegReq) CanDecode() gopacket.LayerClass {
+	return LayerTypeDOCSISRegReq
+}
+
+// NextLayerType returns the layer type contained by this DecodingLayer.
+func (docsis *DOCSISRegReq) NextLayerType() gopacket.LayerType {
+	return gopacket.LayerTypePayload
+}
+
+func decodeDOCSISRegReq(data []byte, p gopacket.PacketBuilder) error {
+	docsis := &DOCSISRegReq{}
+	err := docsis.DecodeFromBytes(data, p)
+	if err != nil {
+		return err
+	}
+	p.AddLayer(docsis)
+
+	return p.NextDecoder(docsis.NextLayerType())
+}
+
+// DOCSISRegReqMp is a DOCSIS Management Multipart Registration Request.
+type DOCSISRegReqMp struct {
+	layers.BaseLayer
+	Sid            uint16
+	FragmentsTotal byte
+	FragmentNumber byte
+	DOCSISRegEncodings
+}
+
+// LayerType returns LayerTypeDOCSISRegReqMp
+func (docsis *DOCSISRegReqMp) LayerType() gopacket.LayerType {
+	return LayerTypeDOCSISRegReqMp
+}
+
+// DecodeFromBytes decodes the given bytes into this layer.
+func (docsis *DOCSISRegReqMp) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
+	if len(data) < 4 {
+		return fmt.Errorf("docsis reg req mp packet is too small for the header")
+	}
+
+	docsis.Sid = binary.BigEndian.Uint16(data[0:2])
+	docsis.FragmentsTotal = data[2]
+	docsis.FragmentNumber = data[3]
+
+	if err := docsis.parseTLV(data[4:]); err != nil {
+		return err
+	}
+
+	docsis.Contents = data[:]
+	docsis.Payload = data[:0]
+
+	return nil
+}
+
+// CanDecode returns the set of layer types that this DecodingLayer can decode.
+func (docsis *DOCSISRegReqMp) CanDecode() gopacket.LayerClass {
+	return LayerTypeDOCSISRegReqMp
+}
+
+// NextLayerType returns the layer type contained by this DecodingLayer.
+func (docsis *DOCSISRegReqMp) NextLayerType() gopacket.LayerType {
+	return gopacket.LayerTypePayload
+}
+
+func decodeDOCSISRegReqMp(data []byte, p gopacket.PacketBuilder) error {
+	docsis := &DOCSISRegReqMp{}
+	err := docsis.DecodeFromBytes(data, p)
+	if err != nil {
+		return err
+	}
+	p.AddLayer(docsis)
+
+	return p.NextDecoder(docsis.NextLayerType())
+}
+
+// DOCSISRegAck is a DOCSIS Management Registration Acknowledge.
+type DOCSISRegAck struct {
+	layers.BaseLayer
+	Sid      uint16
+	Response byte
+	DOCSISRegEncodings
+}
+
+// LayerType returns LayerTypeDOCSISRegAck
+func (docsis *DOCSISRegAck) LayerType() gopacket.LayerType {
+	return LayerTypeDOCSISRegAck
+}
+
+// DecodeFromBytes decodes the given bytes into this layer.
+func (docsis *DOCSISRegAck) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
+	if len(data) < 3 {
+		return fmt.Errorf("docsis reg ack packet is too small for the header")
+	}
+
+	docsis.Sid = binary.BigEndian.Uint16(data[0:2])
+	docsis.Response = data[2]
+
+	if err := docsis.parseTLV(data[3:]); err != nil {
+		return err
+	}
+
+	docsis.Contents = data[:]
+	docsis.Payload = data[:0]
+
+	return nil
+}
+
+// CanDecode returns the set of layer types that this DecodingLayer can decode.
+func (docsis *DOCSISRegAck) CanDecode() gopacket.LayerClass {
+	return LayerTypeDOCSISRegAck
+}
+
+// NextLayerType returns the layer type contained by this DecodingLayer.
+func (docsis *DOCSISRegAck) NextLayerType() gopacket.LayerType {
+	return gopacket.LayerTypePayload
+}
+
+func decodeDOCSISRegAck(data []byte, p gopacket.PacketBuilder) error {
+	docsis := &DOCSISRegAck{}
+	err := docsis.DecodeFromBytes(data, p)
+	if err != nil {
+		return err
+	}
+	p.AddLayer(docsis)
+
+	return p.NextDecoder(docsis.NextLayerType())
+}
@@ -11,14 +11,26 @@ import (
 // LayerTypeDOCSISRegRsp type registration
 var LayerTypeDOCSISRegRsp = gopacket.RegisterLayerType(1005, gopacket.LayerTypeMetadata{Name: "DOCSIS Management Registration Response", Decoder: gopacket.DecodeFunc(decodeDOCSISRegRsp)})
 
+func init() {
+	RegisterManagementType(DocsisManagementRegRsp, ManagementVersionAny, LayerTypeDOCSISRegRsp, func() gopacket.DecodingLayer { return &DOCSISRegRsp{} })
+}
+
 type DOCSISBaseRegRsp struct {
-	Sid                uint16
-	Response           byte
+	Sid      uint16
+	Response byte
+	DOCSISRegEncodings
+}
+
+// DOCSISRegEncodings are the encodings shared by all registration messages.
+type DOCSISRegEncodings struct {
 	DocsisVersion      byte
 	UpstreamChannels   byte
 	DownstreamChannels byte
 	DownstreamMaxRate  uint32
 	UpstreamMaxRate    uint32
+	ServiceFlows       []ServiceFlow
+	// TLVs holds all encodings of the message including the ones not decoded into fields.
+	TLVs TLVs
 }
 
 // DOCSISRegRsp is a DOCSIS Management packet header.
@@ -51,85 +63,116 @@ func (docsis *DOCSISRegRsp) DecodeFromBytes(data []byte, df gopacket.DecodeFeedb
 	return nil
 }
 
-func (docsis *DOCSISBaseRegRsp) parseTLV(tlv []byte) error {
+// docsisConfigSchema describes the nesting of the common configuration settings encodings
+// used by the registration messages and config files.
+var docsisConfigSchema = &TLVSchema{
+	LengthSize: 1,
+	Nested: map[uint8]*TLVSchema{
+		// modem capabilities
+		5: nil,
+		// upstream and downstream packet classification
+		22: classifierSchema,
+		23: classifierSchema,
+		// upstream and downstream service flows
+		24: serviceFlowSchema,
+		25: serviceFlowSchema,
+		// payload header suppression
+		26: nil,
+		// vendor specific
+		43: nil,
+		// transmit channel configuration
+		46: {LengthSize: 1, Nested: map[uint8]*TLVSchema{8: nil}},
+		// service flow SID cluster assignments
+		47: nil,
+		// receive channel configuration
+		49: {LengthSize: 1, Nested: map[uint8]*TLVSchema{4: nil, 5: nil}},
+		// DSID encodings
+		50: nil,
+		// security association
+		51: nil,
+		// channel assignment configuration settings
+		56: nil,
+	},
+}
+
+var classifierSchema = &TLVSchema{
+	LengthSize: 1,
+	Nested: map[uint8]*TLVSchema{
+		// IP, Ethernet LLC and 802.1P/Q packet classification
+		9:  nil,
+		10: nil,
+		11: nil,
+		43: nil,
+	},
+}
+
+var serviceFlowSchema = &TLVSchema{
+	LengthSize: 1,
+	Nested: map[uint8]*TLVSchema{
+		// service flow error encodings
+		5:  nil,
+		43: nil,
+	},
+}
+
+func (docsis *DOCSISRegEncodings) parseTLV(data []byte) error {
 	docsisVersion := byte(0)
 	upstreamChannels := byte(0)
 	downstreamChannels := byte(0)
 	upstreamMaxRate := uint32(0)
 	downstreamMaxRate := uint32(0)
 
-	var tlvTypeLen int
-	for i := 0; i < len(tlv); i += tlvTypeLen + 2 {
-		if len(tlv) < (i + 2) {
-			return fmt.Errorf("tlv header too small")
-		}
-
-		tlvType := tlv[i]
-		tlvTypeLen = int(tlv[i+1])
-		if len(tlv) < (i + 2 + tlvTypeLen) {
-			return fmt.Errorf("tlv too small")
-		}
-
-		// only parse inner structure if the tlvType has one and we are interested in the content
-		if tlvType != 5 && tlvType != 24 && tlvType != 25 {
-			continue
-		}
-
-		tlvInner := tlv[i+2 : i+2+tlvTypeLen]
-		var tlvInnerTypeLen int
-		var flowRef uint16
-		var maxSustainedRate uint32
-		for j := 0; j < len(tlvInner); j += tlvInnerTypeLen + 2 {
-			if len(tlvInner) < (j + 2) {
-				return fmt.Errorf("tlv inner header too small")
-			}
-			tlvInnerType := tlvInner[j]
-			tlvInnerTypeLen = int(tlvInner[j+1])
-			if len(tlvInner) < (j + 2 + tlvInnerTypeLen) {
-				return fmt.Errorf("tlv inner too small")
-			}
+	tlvs, err := DecodeTLVs(data, docsisConfigSchema)
+	if err != nil {
+		return err
+	}
 
-			innerData := tlvInner[j+2 : j+2+tlvInnerTypeLen]
+	for _, tlv := range tlvs {
+		switch tlv.Type {
+		case 5:
+			for _, inner := range tlv.Children {
+				if inner.Type == 2 {
+					docsisVersion, err = inner.Uint8()
+				} else if inner.Type == 24 {
+					upstreamChannels, err = inner.Uint8()
+				} else if inner.Type == 29 {
+					downstreamChannels, err = inner.Uint8()
+				}
 
-			if tlvType == 5 {
-				if tlvInnerType == 2 {
-					if tlvInnerTypeLen != 1 {
-						return fmt.Errorf("docsis reg esp tlv inner type len too small")
-					}
-					docsisVersion = innerData[0]
-				} else if tlvInnerType == 24 {
-					if tlvInnerTypeLen != 1 {
-						return fmt.Errorf("docsis reg esp tlv inner type len too small")
-					}
-					upstreamChannels = innerData[0]
-				} else if tlvInnerType == 29 {
-					if tlvInnerTypeLen != 1 {
-						return fmt.Errorf("docsis reg esp tlv inner type len too small")
-					}
-					downstreamChannels = innerData[0]
+				if err != nil {
+					return fmt.Errorf("docsis reg rsp tlv inner type len too small")
 				}
-			} else if tlvType == 24 || tlvType == 25 {
-				if tlvInnerType == 1 {
-					if tlvInnerTypeLen != 2 {
-						return fmt.Errorf("docsis reg esp tlv inner type len too small")
+			}
+		case 24, 25:
+			var flowRef uint16
+			var maxSustainedRate uint32
+			for _, inner := range tlv.Children {
+				if inner.Type == 1 {
+					if flowRef, err = inner.Uint16(); err != nil {
+						return fmt.Errorf("docsis reg rsp tlv inner type len too small")
 					}
-					flowRef = binary.BigEndian.Uint16(innerData)
-				} else if tlvInnerType == 8 {
-					if tlvInnerTypeLen != 4 {
-						return fmt.Errorf("docsis reg esp tlv inner type len too small")
+				} else if inner.Type == 8 {
+					if maxSustainedRate, err = inner.Uint32(); err != nil {
+						return fmt.Errorf("docsis reg rsp tlv inner type len too small")
 					}
-					maxSustainedRate = binary.BigEndian.Uint32(innerData)
 				}
 			}
-		}
 
-		if tlvType == 24 && flowRef == 1 && maxSustainedRate != 0 {
-			upstreamMaxRate = maxSustainedRate
-		} else if tlvType == 25 && flowRef == 2 && maxSustainedRate != 0 {
-			downstreamMaxRate = maxSustainedRate
+			if tlv.Type == 24 && flowRef == 1 && maxSustainedRate != 0 {
+				upstreamMaxRate = maxSustainedRate
+			} else if tlv.Type == 25 && flowRef == 2 && maxSustainedRate != 0 {
+				downstreamMaxRate = maxSustainedRate
+			}
 		}
 	}
 
+	serviceFlows, err := parseServiceFlows(tlvs)
+	if err != nil {
+		return err
+	}
+
+	docsis.TLVs = tlvs
+	docsis.ServiceFlows = serviceFlows
 	docsis.DocsisVersion = docsisVersion
 	docsis.UpstreamChannels = upstreamChannels
 	docsis.DownstreamChannels = downstreamChannels
@@ -139,6 +182,41 @@ func (docsis *DOCSISBaseRegRsp) parseTLV(tlv []byte) error {
 	return nil
 }
 
+// encodeTLV serializes the encodings. The service flow encodings are generated from ServiceFlows,
+// all other encodings are taken from TLVs. Summary fields like DocsisVersion aren't serialized.
+func (docsis *DOCSISRegEncodings) encodeTLV() ([]byte, error) {
+	var tlvs TLVs
+	for _, tlv := range docsis.TLVs {
+		if tlv.Type != 24 && tlv.Type != 25 {
+			tlvs = append(tlvs, tlv)
+		}
+	}
+	for _, flow := range docsis.ServiceFlows {
+		tlvs = append(tlvs, flow.encode())
+	}
+
+	return tlvs.Encode(docsisConfigSchema)
+}
+
+// SerializeTo writes the serialized form of this layer into the SerializationBuffer.
+func (docsis *DOCSISRegRsp) SerializeTo(b gopacket.SerializeBuffer, opts gopacket.SerializeOptions) error {
+	encodings, err := docsis.encodeTLV()
+	if err != nil {
+		return err
+	}
+
+	bytes, err := b.PrependBytes(3 + len(encodings))
+	if err != nil {
+		return err
+	}
+
+	binary.BigEndian.PutUint16(bytes[0:2], docsis.Sid)
+	bytes[2] = docsis.Response
+	copy(bytes[3:], encodings)
+
+	return nil
+}
+
 // CanDecode returns the set of layer types that this DecodingLayer can decode.
 func (docsis *DOCSISRegRsp) CanDecode() gopacket.LayerClass {
 	return LayerTypeDOCSISRegRsp
@@ -0,0 +1,39 @@
+package main
+
+import "testing"
+
+func TestDOCSISRegRspMalformedNestedTLV(t *testing.T) {
+	data := []byte{
+		0x12, 0x34, 0x00, // sid, response
+		0x05, 0x03, 0x02, 0x01, 0x03, // modem capabilities, DOCSIS 3.0
+		0x2b, 0x03, 0x08, 0x05, 0x00, // vendor specific with a sub-TLV running past its parent
+		0x18, 0x07, 0x01, 0x02, 0x00, 0x01, 0x06, 0x01, 0x07, // upstream service flow reference 1
+	}
+
+	rsp := &DOCSISRegRsp{}
+	if err := rsp.DecodeFromBytes(data, nil); err != nil {
+		t.Fatal(err)
+	}
+	if rsp.DocsisVersion != 3 || len(rsp.ServiceFlows) != 1 || rsp.ServiceFlows[0].Reference != 1 {
+		t.Errorf("decoded %+v", rsp.DOCSISBaseRegRsp)
+	}
+
+	vendor, ok := rsp.TLVs.Get(43)
+	if !ok {
+		t.Fatal("vendor specific tlv is missing")
+	}
+	if vendor.Err == nil || vendor.Children != nil {
+		t.Errorf("vendor specific tlv has error %v and children %v, want an error and no children", vendor.Err, vendor.Children)
+	}
+	if len(vendor.Value) != 3 {
+		t.Errorf("vendor specific value is %x, want the 3 raw bytes", vendor.Value)
+	}
+
+	encoded, err := rsp.TLVs.Encode(docsisConfigSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(encoded) != string(data[3:]) {
+		t.Errorf("encoded %x, want %x", encoded, data[3:])
+	}
+}
@@ -11,6 +11,10 @@ import (
 // LayerTypeDOCSISRegRspMp type registration
 var LayerTypeDOCSISRegRspMp = gopacket.RegisterLayerType(1004, gopacket.LayerTypeMetadata{Name: "DOCSIS Management Multipart Registration Response", Decoder: gopacket.DecodeFunc(decodeDOCSISRegRspMp)})
 
+func init() {
+	RegisterManagementType(DocsisManagementRegRspMp, ManagementVersionAny, LayerTypeDOCSISRegRspMp, func() gopacket.DecodingLayer { return &DOCSISRegRspMp{} })
+}
+
 // DOCSISRegRspMp is a DOCSIS Management packet header.
 type DOCSISRegRspMp struct {
 	layers.BaseLayer
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// LayerTypeDOCSISSync type registration
+var LayerTypeDOCSISSync = gopacket.RegisterLayerType(1008, gopacket.LayerTypeMetadata{Name: "DOCSIS Management Time Synchronization", Decoder: gopacket.DecodeFunc(decodeDOCSISSync)})
+
+func init() {
+	RegisterManagementType(DocsisManagementSync, ManagementVersionAny, LayerTypeDOCSISSync, func() gopacket.DecodingLayer { return &DOCSISSync{} })
+}
+
+// DOCSISSync is a DOCSIS Management SYNC message.
+type DOCSISSync struct {
+	layers.BaseLayer
+	// Timestamp is the CMTS timestamp in units of the 10.24 MHz master clock.
+	Timestamp uint32
+}
+
+// LayerType returns LayerTypeDOCSISSync
+func (docsis *DOCSISSync) LayerType() gopacket.LayerType {
+	return LayerTypeDOCSISSync
+}
+
+// DecodeFromBytes decodes the given bytes into this layer.
+func (docsis *DOCSISSync) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
+	if len(data) < 4 {
+		return fmt.Errorf("docsis sync packet is too small for the timestamp")
+	}
+
+	docsis.Timestamp = binary.BigEndian.Uint32(data[0:4])
+
+	docsis.Contents = data[:4]
+	docsis.Payload = data[4:]
+
+	return nil
+}
+
+// SerializeTo writes the serialized form of this layer into the SerializationBuffer.
+func (docsis *DOCSISSync) SerializeTo(b gopacket.SerializeBuffer, opts gopacket.SerializeOptions) error {
+	bytes, err := b.PrependBytes(4)
+	if err != nil {
+		return err
+	}
+
+	binary.BigEndian.PutUint32(bytes, docsis.Timestamp)
+
+	return nil
+}
+
+// CanDecode returns the set of layer types that this DecodingLayer can decode.
+func (docsis *DOCSISSync) CanDecode() gopacket.LayerClass {
+	return LayerTypeDOCSISSync
+}
+
+// NextLayerType returns the layer type contained by this DecodingLayer.
+func (docsis *DOCSISSync) NextLayerType() gopacket.LayerType {
+	return gopacket.LayerTypePayload
+}
+
+func decodeDOCSISSync(data []byte, p gopacket.PacketBuilder) error {
+	docsis := &DOCSISSync{}
+	err := docsis.DecodeFromBytes(data, p)
+	if err != nil {
+		return err
+	}
+	p.AddLayer(docsis)
+
+	return p.NextDecoder(docsis.NextLayerType())
+}
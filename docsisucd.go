@@ -0,0 +1,502 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+// LayerTypeDOCSISUCD type registration
+var LayerTypeDOCSISUCD = gopacket.RegisterLayerType(1009, gopacket.LayerTypeMetadata{Name: "DOCSIS Management Upstream Channel Descriptor", Decoder: gopacket.DecodeFunc(decodeDOCSISUCD)})
+
+// LayerTypeDOCSISUCD29 type registration
+var LayerTypeDOCSISUCD29 = gopacket.RegisterLayerType(1010, gopacket.LayerTypeMetadata{Name: "DOCSIS Management Upstream Channel Descriptor Type 29", Decoder: gopacket.DecodeFunc(decodeDOCSISUCD29)})
+
+// LayerTypeDOCSISUCD35 type registration
+var LayerTypeDOCSISUCD35 = gopacket.RegisterLayerType(1011, gopacket.LayerTypeMetadata{Name: "DOCSIS Management Upstream Channel Descriptor Type 35", Decoder: gopacket.DecodeFunc(decodeDOCSISUCD35)})
+
+// LayerTypeDOCSISUCD51 type registration
+var LayerTypeDOCSISUCD51 = gopacket.RegisterLayerType(1012, gopacket.LayerTypeMetadata{Name: "DOCSIS Management Upstream Channel Descriptor Type 51", Decoder: gopacket.DecodeFunc(decodeDOCSISUCD51)})
+
+func init() {
+	RegisterManagementType(DocsisManagementUCD, ManagementVersionAny, LayerTypeDOCSISUCD, func() gopacket.DecodingLayer { return &DOCSISUCD{} })
+	RegisterManagementType(DocsisManagementUCD29, ManagementVersionAny, LayerTypeDOCSISUCD29, func() gopacket.DecodingLayer { return &DOCSISUCD29{} })
+	RegisterManagementType(DocsisManagementUCD35, ManagementVersionAny, LayerTypeDOCSISUCD35, func() gopacket.DecodingLayer { return &DOCSISUCD35{} })
+	RegisterManagementType(DocsisManagementUCD51, ManagementVersionAny, LayerTypeDOCSISUCD51, func() gopacket.DecodingLayer { return &DOCSISUCD51{} })
+}
+
+// symbolRateUnit is the unit of the UCD symbol rate TLV in symbols per second
+const symbolRateUnit = 160000
+
+var ucdSchema = &TLVSchema{LengthSize: 1}
+
+// UpstreamBurstDescriptor describes the burst profile of an interval usage code.
+// TDMA/S-CDMA burst descriptors (TLV 4 and 5) and OFDMA ones (TLV 23) use different attributes.
+type UpstreamBurstDescriptor struct {
+	IUC                    uint8
+	ModulationType         uint8
+	DifferentialEncoding   uint8
+	PreambleLength         uint16
+	PreambleValueOffset    uint16
+	FECErrorCorrection     uint8
+	FECCodewordLength      uint8
+	ScramblerSeed          uint16
+	MaxBurstSize           uint8
+	GuardTimeSize          uint8
+	LastCodewordLength     uint8
+	Scrambler              uint8
+	RSInterleaverDepth     uint8
+	RSInterleaverBlockSize uint16
+	PreambleType           uint8
+	SCDMASpreader          uint8
+	SCDMACodesPerSubframe  uint8
+	SCDMAFramerStepSize    uint8
+	TCMEncoding            uint8
+	// OFDMA attributes
+	InitialRangingSubcarriers uint16
+	FineRangingSubcarriers    uint16
+	OFDMAProfile              []OFDMAProfileEntry
+	// IRPowerControlStart and IRPowerControlStepSize are in units of 0.25 dB
+	IRPowerControlStart    uint8
+	IRPowerControlStepSize uint8
+	TLVs                   TLVs
+}
+
+// OFDMAProfileEntry is the modulation of a run of minislots in an OFDMA burst profile.
+type OFDMAProfileEntry struct {
+	DataSymbolModulation uint8
+	PilotPattern         uint8
+	// AdditionalMinislots is the number of following minislots using the same entry
+	AdditionalMinislots uint8
+}
+
+// DOCSISBaseUCD contains the fields shared by all UCD variants.
+type DOCSISBaseUCD struct {
+	UpstreamChannelID   uint8
+	ConfigChangeCount   uint8
+	MiniSlotSize        uint8
+	DownstreamChannelID uint8
+	// SymbolRate in symbols per second
+	SymbolRate uint32
+	// Frequency is the center frequency in Hz
+	Frequency               uint32
+	PreamblePattern         []byte
+	ExtendedPreamblePattern []byte
+	SCDMAEnabled            bool
+	RangingRequired         uint8
+	// S-CDMA channel parameters
+	SCDMASpreadingIntervalsPerFrame uint8
+	SCDMACodesPerMiniSlot           uint8
+	SCDMAActiveCodes                uint8
+	SCDMACodeHoppingSeed            uint16
+	SCDMAUSRatioNumerator           uint16
+	SCDMAUSRatioDenominator         uint16
+	SCDMATimestampSnapshot          []byte
+	MaintainPowerSpectralDensity    uint8
+	SCDMAMaxScheduledCodesEnabled   uint8
+	RangingHoldOffPriority          uint32
+	RangingChannelClassID           uint32
+	SCDMASelectionMode              uint8
+	SCDMASelectionString            []byte
+	HigherUCDPresent                uint8
+	// OFDMA channel parameters of type 51 UCDs
+	OFDMACyclicPrefixSize  uint8
+	OFDMARolloffPeriodSize uint8
+	SubcarrierSpacing      uint8
+	SubcarrierZeroFreq     uint32
+	SymbolsPerFrame        uint8
+	BurstDescriptors       []UpstreamBurstDescriptor
+	TLVs                   TLVs
+}
+
+func (docsis *DOCSISBaseUCD) parseUCD(data []byte) error {
+	if len(data) < 4 {
+		return fmt.Errorf("docsis ucd packet is too small for the header")
+	}
+
+	*docsis = DOCSISBaseUCD{BurstDescriptors: docsis.BurstDescriptors[:0]}
+	docsis.UpstreamChannelID = data[0]
+	docsis.ConfigChangeCount = data[1]
+	docsis.MiniSlotSize = data[2]
+	docsis.DownstreamChannelID = data[3]
+
+	tlvs, err := DecodeTLVs(data[4:], ucdSchema)
+	if err != nil {
+		return err
+	}
+
+	for _, tlv := range tlvs {
+		switch tlv.Type {
+		case 1:
+			var symbolRate uint8
+			symbolRate, err = tlv.Uint8()
+			docsis.SymbolRate = uint32(symbolRate) * symbolRateUnit
+		case 2:
+			docsis.Frequency, err = tlv.Uint32()
+		case 3:
+			docsis.PreamblePattern = tlv.Value
+		case 4, 5, 23:
+			var burst UpstreamBurstDescriptor
+			if burst, err = parseBurstDescriptor(tlv); err == nil {
+				docsis.BurstDescriptors = append(docsis.BurstDescriptors, burst)
+			}
+		case 6:
+			docsis.ExtendedPreamblePattern = tlv.Value
+		case 7:
+			var enabled uint8
+			enabled, err = tlv.Uint8()
+			docsis.SCDMAEnabled = (enabled == 1)
+		case 8:
+			docsis.SCDMASpreadingIntervalsPerFrame, err = tlv.Uint8()
+		case 9:
+			docsis.SCDMACodesPerMiniSlot, err = tlv.Uint8()
+		case 10:
+			docsis.SCDMAActiveCodes, err = tlv.Uint8()
+		case 11:
+			docsis.SCDMACodeHoppingSeed, err = tlv.Uint16()
+		case 12:
+			docsis.SCDMAUSRatioNumerator, err = tlv.Uint16()
+		case 13:
+			docsis.SCDMAUSRatioDenominator, err = tlv.Uint16()
+		case 14:
+			if len(tlv.Value) != 9 {
+				err = fmt.Errorf("tlv %d has length %d instead of 9", tlv.Type, len(tlv.Value))
+			}
+			docsis.SCDMATimestampSnapshot = tlv.Value
+		case 15:
+			docsis.MaintainPowerSpectralDensity, err = tlv.Uint8()
+		case 16:
+			docsis.RangingRequired, err = tlv.Uint8()
+		case 17:
+			docsis.SCDMAMaxScheduledCodesEnabled, err = tlv.Uint8()
+		case 18:
+			docsis.RangingHoldOffPriority, err = tlv.Uint32()
+		case 19:
+			docsis.RangingChannelClassID, err = tlv.Uint32()
+		case 20:
+			docsis.SCDMASelectionMode, err = tlv.Uint8()
+		case 21:
+			if len(tlv.Value) != 16 {
+				err = fmt.Errorf("tlv %d has length %d instead of 16", tlv.Type, len(tlv.Value))
+			}
+			docsis.SCDMASelectionString = tlv.Value
+		case 22:
+			docsis.HigherUCDPresent, err = tlv.Uint8()
+		case 26:
+			docsis.OFDMACyclicPrefixSize, err = tlv.Uint8()
+		case 27:
+			docsis.OFDMARolloffPeriodSize, err = tlv.Uint8()
+		case 28:
+			docsis.SubcarrierSpacing, err = tlv.Uint8()
+		case 29:
+			docsis.SubcarrierZeroFreq, err = tlv.Uint32()
+		case 32:
+			docsis.SymbolsPerFrame, err = tlv.Uint8()
+		}
+
+		if err != nil {
+			return fmt.Errorf("docsis ucd: %w", err)
+		}
+	}
+
+	docsis.TLVs = tlvs
+
+	return nil
+}
+
+func parseBurstDescriptor(tlv TLV) (UpstreamBurstDescriptor, error) {
+	var burst UpstreamBurstDescriptor
+
+	if len(tlv.Value) < 1 {
+		return burst, fmt.Errorf("burst descriptor is too small for the iuc")
+	}
+	burst.IUC = tlv.Value[0]
+
+	tlvs, err := DecodeTLVs(tlv.Value[1:], ucdSchema)
+	if err != nil {
+		return burst, err
+	}
+	burst.TLVs = tlvs
+
+	if tlv.Type == 23 {
+		return burst, parseOFDMABurstAttributes(&burst, tlvs)
+	}
+
+	for _, attribute := range tlvs {
+		switch attribute.Type {
+		case 1:
+			burst.ModulationType, err = attribute.Uint8()
+		case 2:
+			burst.DifferentialEncoding, err = attribute.Uint8()
+		case 3:
+			burst.PreambleLength, err = attribute.Uint16()
+		case 4:
+			burst.PreambleValueOffset, err = attribute.Uint16()
+		case 5:
+			burst.FECErrorCorrection, err = attribute.Uint8()
+		case 6:
+			burst.FECCodewordLength, err = attribute.Uint8()
+		case 7:
+			burst.ScramblerSeed, err = attribute.Uint16()
+		case 8:
+			burst.MaxBurstSize, err = attribute.Uint8()
+		case 9:
+			burst.GuardTimeSize, err = attribute.Uint8()
+		case 10:
+			burst.LastCodewordLength, err = attribute.Uint8()
+		case 11:
+			burst.Scrambler, err = attribute.Uint8()
+		case 12:
+			burst.RSInterleaverDepth, err = attribute.Uint8()
+		case 13:
+			burst.RSInterleaverBlockSize, err = attribute.Uint16()
+		case 14:
+			burst.PreambleType, err = attribute.Uint8()
+		case 15:
+			burst.SCDMASpreader, err = attribute.Uint8()
+		case 16:
+			burst.SCDMACodesPerSubframe, err = attribute.Uint8()
+		case 17:
+			burst.SCDMAFramerStepSize, err = attribute.Uint8()
+		case 18:
+			burst.TCMEncoding, err = attribute.Uint8()
+		}
+
+		if err != nil {
+			return burst, fmt.Errorf("burst descriptor for iuc %d: %w", burst.IUC, err)
+		}
+	}
+
+	return burst, nil
+}
+
+func parseOFDMABurstAttributes(burst *UpstreamBurstDescriptor, tlvs TLVs) error {
+	var err error
+	for _, attribute := range tlvs {
+		switch attribute.Type {
+		case 19:
+			burst.InitialRangingSubcarriers, err = attribute.Uint16()
+		case 20:
+			burst.FineRangingSubcarriers, err = attribute.Uint16()
+		case 21:
+			if len(attribute.Value)%2 != 0 {
+				err = fmt.Errorf("tlv %d has length %d which isn't a multiple of 2", attribute.Type, len(attribute.Value))
+				break
+			}
+			for i := 0; i < len(attribute.Value); i += 2 {
+				burst.OFDMAProfile = append(burst.OFDMAProfile, OFDMAProfileEntry{
+					DataSymbolModulation: attribute.Value[i] >> 4,
+					PilotPattern:         attribute.Value[i] & 0x0f,
+					AdditionalMinislots:  attribute.Value[i+1],
+				})
+			}
+		case 22:
+			if len(attribute.Value) != 2 {
+				err = fmt.Errorf("tlv %d has length %d instead of 2", attribute.Type, len(attribute.Value))
+				break
+			}
+			burst.IRPowerControlStart = attribute.Value[0]
+			burst.IRPowerControlStepSize = attribute.Value[1]
+		}
+
+		if err != nil {
+			return fmt.Errorf("ofdma burst descriptor for iuc %d: %w", burst.IUC, err)
+		}
+	}
+
+	return nil
+}
+
+// DOCSISUCD is a DOCSIS Management Upstream Channel Descriptor (type 2).
+type DOCSISUCD struct {
+	layers.BaseLayer
+	DOCSISBaseUCD
+}
+
+// LayerType returns LayerTypeDOCSISUCD
+func (docsis *DOCSISUCD) LayerType() gopacket.LayerType {
+	return LayerTypeDOCSISUCD
+}
+
+// DecodeFromBytes decodes the given bytes into this layer.
+func (docsis *DOCSISUCD) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
+	if err := docsis.parseUCD(data); err != nil {
+		return err
+	}
+
+	docsis.Contents = data[:]
+	docsis.Payload = data[:0]
+
+	return nil
+}
+
+// CanDecode returns the set of layer types that this DecodingLayer can decode.
+func (docsis *DOCSISUCD) CanDecode() gopacket.LayerClass {
+	return LayerTypeDOCSISUCD
+}
+
+// NextLayerType returns the layer type contained by this DecodingLayer.
+func (docsis *DOCSISUCD) NextLayerType() gopacket.LayerType {
+	return gopacket.LayerTypePayload
+}
+
+func decodeDOCSISUCD(data []byte, p gopacket.PacketBuilder) error {
+	docsis := &DOCSISUCD{}
+	err := docsis.DecodeFromBytes(data, p)
+	if err != nil {
+		return err
+	}
+	p.AddLayer(docsis)
+
+	return p.NextDecoder(docsis.NextLayerType())
+}
+
+// DOCSISUCD29 is a DOCSIS Management Upstream Channel Descriptor for DOCSIS 2.0 channels (type 29).
+type DOCSISUCD29 struct {
+	layers.BaseLayer
+	DOCSISBaseUCD
+}
+
+// LayerType returns LayerTypeDOCSISUCD29
+func (docsis *DOCSISUCD29) LayerType() gopacket.LayerType {
+	return LayerTypeDOCSISUCD29
+}
+
+// DecodeFromBytes decodes the given bytes into this layer.
+func (docsis *DOCSISUCD29) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
+	if err := docsis.parseUCD(data); err != nil {
+		return err
+	}
+
+	docsis.Contents = data[:]
+	docsis.Payload = data[:0]
+
+	return nil
+}
+
+// CanDecode returns the set of layer types that this DecodingLayer can decode.
+func (docsis *DOCSISUCD29) CanDecode() gopacket.LayerClass {
+	return LayerTypeDOCSISUCD29
+}
+
+// NextLayerType returns the layer type contained by this DecodingLayer.
+func (docsis *DOCSISUCD29) NextLayerType() gopacket.LayerType {
+	return gopacket.LayerTypePayload
+}
+
+func decodeDOCSISUCD29(data []byte, p gopacket.PacketBuilder) error {
+	docsis := &DOCSISUCD29{}
+	err := docsis.DecodeFromBytes(data, p)
+	if err != nil {
+		return err
+	}
+	p.AddLayer(docsis)
+
+	return p.NextDecoder(docsis.NextLayerType())
+}
+
+// DOCSISUCD35 is a DOCSIS Management Upstream Channel Descriptor for DOCSIS 3.0 channels (type 35).
+type DOCSISUCD35 struct {
+	layers.BaseLayer
+	DOCSISBaseUCD
+}
+
+// LayerType returns LayerTypeDOCSISUCD35
+func (docsis *DOCSISUCD35) LayerType() gopacket.LayerType {
+	return LayerTypeDOCSISUCD35
+}
+
+// DecodeFromBytes decodes the given bytes into this layer.
+func (docsis *DOCSISUCD35) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
+	if err := docsis.parseUCD(data); err != nil {
+		return err
+	}
+
+	docsis.Contents = data[:]
+	docsis.Payload = data[:0]
+
+	return nil
+}
+
+// CanDecode returns the set of layer types that this DecodingLayer can decode.
+func (docsis *DOCSISUCD35) CanDecode() gopacket.LayerClass {
+	return LayerTypeDOCSISUCD35
+}
+
+// NextLayerType returns the layer type contained by this DecodingLayer.
+func (docsis *DOCSISUCD35) NextLayerType() gopacket.LayerType {
+	return gopacket.LayerTypePayload
+}
+
+func decodeDOCSISUCD35(data []byte, p gopacket.PacketBuilder) error {
+	docsis := &DOCSISUCD35{}
+	err := docsis.DecodeFromBytes(data, p)
+	if err != nil {
+		return err
+	}
+	p.AddLayer(docsis)
+
+	return p.NextDecoder(docsis.NextLayerType())
+}
+
+// DOCSISUCD51 is a DOCSIS Management Upstream Channel Descriptor for OFDMA channels (type 51).
+type DOCSISUCD51 struct {
+	layers.BaseLayer
+	DOCSISBaseUCD
+}
+
+// LayerType returns LayerTypeDOCSISUCD51
+func (docsis *DOCSISUCD51) LayerType() gopacket.LayerType {
+	return LayerTypeDOCSISUCD51
+}
+
+// DecodeFromBytes decodes the given bytes into this layer.
+func (docsis *DOCSISUCD51) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) error {
+	if err := docsis.parseUCD(data); err != nil {
+		return err
+	}
+
+	docsis.Contents = data[:]
+	docsis.Payload = data[:0]
+
+	return nil
+}
+
+// CanDecode returns the set of layer types that this DecodingLayer can decode.
+func (docsis *DOCSISUCD51) CanDecode() gopacket.LayerClass {
+	return LayerTypeDOCSISUCD51
+}
+
+// NextLayerType returns the layer type contained by this DecodingLayer.
+func (docsis *DOCSISUCD51) NextLayerType() gopacket.LayerType {
+	return gopacket.LayerTypePayload
+}
+
+func decodeDOCSISUCD51(data []byte, p gopacket.PacketBuilder) error {
+	docsis := &DOCSISUCD51{}
+	err := docsis.DecodeFromBytes(data, p)
+	if err != nil {
+		return err
+	}
+	p.AddLayer(docsis)
+
+	return p.NextDecoder(docsis.NextLayerType())
+}
+
+// clone returns a deep copy that doesn't reference the decoded packet data anymore.
+func (docsis *DOCSISBaseUCD) clone() DOCSISBaseUCD {
+	result := *docsis
+	result.PreamblePattern = append([]byte(nil), docsis.PreamblePattern...)
+	result.ExtendedPreamblePattern = append([]byte(nil), docsis.ExtendedPreamblePattern...)
+	result.SCDMATimestampSnapshot = append([]byte(nil), docsis.SCDMATimestampSnapshot...)
+	result.SCDMASelectionString = append([]byte(nil), docsis.SCDMASelectionString...)
+	result.TLVs = docsis.TLVs.Clone()
+	result.BurstDescriptors = make([]UpstreamBurstDescriptor, len(docsis.BurstDescriptors))
+	for i, burst := range docsis.BurstDescriptors {
+		result.BurstDescriptors[i] = burst
+		result.BurstDescriptors[i].OFDMAProfile = append([]OFDMAProfileEntry(nil), burst.OFDMAProfile...)
+		result.BurstDescriptors[i].TLVs = burst.TLVs.Clone()
+	}
+	return result
+}
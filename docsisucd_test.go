@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDOCSISUCDSCDMAChannel(t *testing.T) {
+	selection := bytes.Repeat([]byte{0xff}, 16)
+	snapshot := []byte{0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}
+
+	data := []byte{0x03, 0x02, 0x04, 0x01} // upstream channel, change count, mini slot size, downstream channel
+	data = append(data,
+		0x01, 0x01, 0x20, // 5.12 Msym/s
+		0x07, 0x01, 0x01, // S-CDMA enabled
+		0x08, 0x01, 0x10,
+		0x09, 0x01, 0x04,
+		0x0a, 0x01, 0x80,
+		0x0b, 0x02, 0x12, 0x34,
+		0x0c, 0x02, 0x00, 0x02,
+		0x0d, 0x02, 0x00, 0x03)
+	snapshotLength := len(data) + 1
+	data = append(data, 0x0e, 0x09)
+	data = append(data, snapshot...)
+	data = append(data,
+		0x0f, 0x01, 0x01,
+		0x11, 0x01, 0x01,
+		0x12, 0x04, 0x00, 0x00, 0x00, 0x02,
+		0x13, 0x04, 0x00, 0x00, 0x00, 0x05,
+		0x14, 0x01, 0x02,
+		0x15, 0x10)
+	data = append(data, selection...)
+	data = append(data, 0x16, 0x01, 0x01)
+
+	ucd := &DOCSISUCD29{}
+	if err := ucd.DecodeFromBytes(data, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !ucd.SCDMAEnabled || ucd.SymbolRate != 5120000 {
+		t.Errorf("S-CDMA %v at %d sym/s", ucd.SCDMAEnabled, ucd.SymbolRate)
+	}
+	if ucd.SCDMASpreadingIntervalsPerFrame != 16 || ucd.SCDMACodesPerMiniSlot != 4 || ucd.SCDMAActiveCodes != 128 {
+		t.Errorf("spreading intervals %d, codes per mini slot %d, active codes %d",
+			ucd.SCDMASpreadingIntervalsPerFrame, ucd.SCDMACodesPerMiniSlot, ucd.SCDMAActiveCodes)
+	}
+	if ucd.SCDMACodeHoppingSeed != 0x1234 || ucd.SCDMAUSRatioNumerator != 2 || ucd.SCDMAUSRatioDenominator != 3 {
+		t.Errorf("hopping seed %#x, ratio %d/%d", ucd.SCDMACodeHoppingSeed, ucd.SCDMAUSRatioNumerator, ucd.SCDMAUSRatioDenominator)
+	}
+	if !bytes.Equal(ucd.SCDMATimestampSnapshot, snapshot) || !bytes.Equal(ucd.SCDMASelectionString, selection) {
+		t.Errorf("snapshot %x, selection string %x", ucd.SCDMATimestampSnapshot, ucd.SCDMASelectionString)
+	}
+	if ucd.MaintainPowerSpectralDensity != 1 || ucd.SCDMAMaxScheduledCodesEnabled != 1 || ucd.SCDMASelectionMode != 2 || ucd.HigherUCDPresent != 1 {
+		t.Errorf("decoded %+v", ucd.DOCSISBaseUCD)
+	}
+	if ucd.RangingHoldOffPriority != 2 || ucd.RangingChannelClassID != 5 {
+		t.Errorf("ranging hold-off priority %d, channel class %d", ucd.RangingHoldOffPriority, ucd.RangingChannelClassID)
+	}
+
+	// the timestamp snapshot has a fixed size
+	data[snapshotLength] = 0x08
+	if err := ucd.DecodeFromBytes(data, nil); err == nil {
+		t.Error("short timestamp snapshot decoded without error")
+	}
+}
+
+func TestDOCSISUCDOFDMABurstDescriptor(t *testing.T) {
+	data := []byte{
+		0x05, 0x01, 0x08, 0x01, // upstream channel, change count, mini slot size, downstream channel
+		0x1a, 0x01, 0x02, // cyclic prefix
+		0x17, 0x0f, 0x05, // OFDMA burst descriptor for IUC 5
+		0x15, 0x04, 0x83, 0x03, 0x64, 0x00, // 2 profile entries
+		0x13, 0x02, 0x00, 0x34,
+		0x16, 0x02, 0x04, 0x02,
+	}
+
+	ucd := &DOCSISUCD51{}
+	if err := ucd.DecodeFromBytes(data, nil); err != nil {
+		t.Fatal(err)
+	}
+	if ucd.OFDMACyclicPrefixSize != 2 || len(ucd.BurstDescriptors) != 1 {
+		t.Fatalf("decoded %+v", ucd.DOCSISBaseUCD)
+	}
+
+	burst := ucd.BurstDescriptors[0]
+	if burst.IUC != 5 || burst.InitialRangingSubcarriers != 0x34 {
+		t.Errorf("iuc %d with %d initial ranging subcarriers", burst.IUC, burst.InitialRangingSubcarriers)
+	}
+	if burst.IRPowerControlStart != 4 || burst.IRPowerControlStepSize != 2 {
+		t.Errorf("IR power control %d step %d", burst.IRPowerControlStart, burst.IRPowerControlStepSize)
+	}
+	want := []OFDMAProfileEntry{
+		{DataSymbolModulation: 8, PilotPattern: 3, AdditionalMinislots: 3},
+		{DataSymbolModulation: 6, PilotPattern: 4, AdditionalMinislots: 0},
+	}
+	if len(burst.OFDMAProfile) != len(want) {
+		t.Fatalf("profile is %+v, want %+v", burst.OFDMAProfile, want)
+	}
+	for i := range want {
+		if burst.OFDMAProfile[i] != want[i] {
+			t.Errorf("profile entry %d is %+v, want %+v", i, burst.OFDMAProfile[i], want[i])
+		}
+	}
+	if burst.ModulationType != 0 {
+		t.Errorf("OFDMA attributes were decoded as TDMA ones")
+	}
+
+	clone := ucd.clone()
+	ucd.BurstDescriptors[0].OFDMAProfile[0].PilotPattern = 0
+	if clone.BurstDescriptors[0].OFDMAProfile[0].PilotPattern != 3 {
+		t.Error("clone shares the OFDMA profile")
+	}
+}
@@ -38,6 +38,11 @@ func (ethenc *ETHENC) DecodeFromBytes(data []byte, df gopacket.DecodeFeedback) e
 	return nil
 }
 
+// LinkFlow returns a flow of the source and destination MAC addresses.
+func (ethenc *ETHENC) LinkFlow() gopacket.Flow {
+	return gopacket.NewFlow(layers.EndpointMAC, ethenc.SrcMAC, ethenc.DstMAC)
+}
+
 // CanDecode returns the set of layer types that this DecodingLayer can decode.
 func (ethenc *ETHENC) CanDecode() gopacket.LayerClass {
 	return LayerTypeETHENC
@@ -50,5 +55,6 @@ func decodeETHENC(data []byte, p gopacket.PacketBuilder) error {
 		return err
 	}
 	p.AddLayer(ethenc)
+	p.SetLinkLayer(ethenc)
 	return p.NextDecoder(gopacket.LayerTypePayload)
 }
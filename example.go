@@ -4,12 +4,18 @@ package main
 
 import (
 	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"net"
 	"os"
 	"os/signal"
 	"runtime/debug"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
@@ -19,10 +25,12 @@ import (
 )
 
 var docsis DOCSIS
+var docsisCounters DOCSISCounters
 var docsisManagement DOCSISManagement
 var docsisRegRspMp DOCSISRegRspMp
 var docsisRegRsp DOCSISRegRsp
-var parser = gopacket.NewDecodingLayerParser(LayerTypeDOCSIS, &docsis, &docsisManagement, &docsisRegRsp, &docsisRegRspMp)
+var docsisBpkmRsp DOCSISBpkmRsp
+var parser = gopacket.NewDecodingLayerParser(LayerTypeDOCSIS, &docsis, &docsisManagement, &docsisRegRsp, &docsisRegRspMp, &docsisBpkmRsp)
 var decoded = []gopacket.LayerType{}
 
 func decodeLayers(data []byte, decoded *[]gopacket.LayerType) (err error) {
@@ -114,6 +122,74 @@ func modeReadPcap(ctx context.Context, inputFilename string) error {
 	}
 }
 
+func modeDeriveKeys(ctx context.Context, inputFilename string, privateKeyFilename string) error {
+	privateKeyData, err := ioutil.ReadFile(privateKeyFilename)
+	if err != nil {
+		return err
+	}
+	// the key is accepted as PEM or DER
+	if block, _ := pem.Decode(privateKeyData); block != nil {
+		privateKeyData = block.Bytes
+	}
+	privateKey, err := x509.ParsePKCS1PrivateKey(privateKeyData)
+	if err != nil {
+		parsedKey, pkcs8Err := x509.ParsePKCS8PrivateKey(privateKeyData)
+		if pkcs8Err != nil {
+			return err
+		}
+		var ok bool
+		if privateKey, ok = parsedKey.(*rsa.PrivateKey); !ok {
+			return fmt.Errorf("private key isn't a RSA key")
+		}
+	}
+
+	deriver := newBPIKeyDeriver(privateKey)
+	deriver.OnKey = func(mac net.HardwareAddr, key BPIKey) {
+		fmt.Printf("# %s\n%s\n", mac.String(), key.KeyFileLine())
+	}
+
+	inputFile, err := os.Open(inputFilename)
+	if err != nil {
+		return err
+	}
+	defer inputFile.Close()
+
+	pcapReader, err := pcapgo.NewReader(inputFile)
+	if err != nil {
+		return err
+	}
+
+	for {
+		data, _, err := pcapReader.ReadPacketData()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+
+			return err
+		}
+
+		if err := decodeLayers(data, &decoded); err != nil {
+			continue
+		}
+
+		for _, layerType := range decoded {
+			if layerType != LayerTypeDOCSISBpkmRsp {
+				continue
+			}
+			if err := deriver.AddResponse(docsisManagement.DstMAC, &docsisBpkmRsp); err != nil {
+				fmt.Fprintln(os.Stderr, "BPKM response for", docsisManagement.DstMAC.String(), "failed:", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+	}
+}
+
 func modeReadDvb(ctx context.Context, frequencyStr string) error {
 	var freq int
 	var err error
@@ -147,6 +223,43 @@ func modeReadDvb(ctx context.Context, frequencyStr string) error {
 	return nil
 }
 
+func modeReadDvbBonded(ctx context.Context, frequenciesStr string) error {
+	// one dvb device per frequency, e.g. "602,610,618"
+	var readers []io.Reader
+	for deviceNum, frequencyStr := range strings.Split(frequenciesStr, ",") {
+		freq, err := strconv.Atoi(frequencyStr)
+		if err != nil {
+			panic(err)
+		}
+
+		if err := tune(deviceNum, dvb.SysDVBCAnnexA, dvb.QAM256, uint32(freq*1000000), 6952000); err != nil {
+			panic(err)
+		}
+
+		sr, err := newStreamReader(deviceNum, 8190)
+		if err != nil {
+			panic(err)
+		}
+		defer sr.Close()
+
+		if err := sr.Start(); err != nil {
+			panic(err)
+		}
+		defer sr.Stop()
+
+		readers = append(readers, &sr)
+	}
+
+	resequencer := newResequencer(50*time.Millisecond, parsePacket)
+	err := resequencer.ReadLoop(ctx, readers...)
+
+	for dsid, stats := range resequencer.Stats() {
+		fmt.Fprintf(os.Stderr, "DSID 0x%05x: delivered %d, lost %d, late %d\n", dsid, stats.Delivered, stats.Lost, stats.Late)
+	}
+
+	return err
+}
+
 func modeBenchmark(frequencyStr string, duration time.Duration) error {
 	var frequency int
 	var err error
@@ -201,6 +314,21 @@ func main() {
 	parser.IgnoreUnsupported = true
 	// we install an own recover handler to print a stacktrace
 	parser.IgnorePanic = true
+	docsis.Counters = &docsisCounters
+
+	// optional key file to decrypt BPI+ encrypted frames
+	if len(os.Args) > 3 && mode != "derivekeys" {
+		keyFile, err := os.Open(os.Args[3])
+		if err != nil {
+			panic(err)
+		}
+		docsis.Decryptor = newBPIDecryptor()
+		err = docsis.Decryptor.LoadKeyFile(keyFile)
+		keyFile.Close()
+		if err != nil {
+			panic(err)
+		}
+	}
 
 	// cancel ctx on SIGTERM / SIGINT allowing graceful shutdown
 	ctx := context.Background()
@@ -224,6 +352,17 @@ func main() {
 	} else if mode == "readdvb" {
 		// capture first dvb device at specified frequency (in mhz)
 		err = modeReadDvb(ctx, parameter)
+	} else if mode == "readdvbbonded" {
+		// capture bonded channels, one dvb device per comma separated frequency (in mhz)
+		err = modeReadDvbBonded(ctx, parameter)
+	} else if mode == "derivekeys" {
+		// derive the TEKs of a modem from a PCAP file with its BPKM exchanges and its RSA private key,
+		// the output is a key file for decryption
+		if len(os.Args) < 4 {
+			fmt.Println("derivekeys needs the private key of the modem")
+			os.Exit(1)
+		}
+		err = modeDeriveKeys(ctx, parameter, os.Args[3])
 	} else if mode == "benchmark" {
 		// calculate average data transfer rate on specified frequency (in mhz)
 		err = modeBenchmark(parameter, 10*time.Second)
@@ -234,5 +373,7 @@ func main() {
 		os.Exit(1)
 	}
 
+	fmt.Fprintf(os.Stderr, "PDUs: %d, CRC errors: %d, HCS errors: %d, truncated: %d\n",
+		docsisCounters.PDUs, docsisCounters.PDUCRCErrors, docsisCounters.HCSErrors, docsisCounters.Truncated)
 	fmt.Fprintf(os.Stderr, "Bye!\n")
 }
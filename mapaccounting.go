@@ -0,0 +1,109 @@
+package main
+
+// SIDUsage are the upstream grants of a single SID.
+type SIDUsage struct {
+	Grants    uint64
+	Minislots uint64
+	// ByIUC splits Minislots by interval usage code
+	ByIUC map[uint8]uint64
+}
+
+// ChannelUsage are the totals of a single upstream channel.
+type ChannelUsage struct {
+	Maps      uint64
+	Minislots uint64
+}
+
+// MapAccounting sums the minislots granted per SID across MAP messages.
+type MapAccounting struct {
+	sids     map[uint16]*SIDUsage
+	channels map[uint8]*ChannelUsage
+}
+
+func newMapAccounting() *MapAccounting {
+	return &MapAccounting{
+		sids:     make(map[uint16]*SIDUsage),
+		channels: make(map[uint8]*ChannelUsage),
+	}
+}
+
+// isDataGrant returns whether the IUC is a grant for upstream data.
+func isDataGrant(iuc uint8) bool {
+	switch iuc {
+	case IUCShortDataGrant, IUCLongDataGrant, IUCAdvPhyShortDataGrant, IUCAdvPhyLongDataGrant, IUCAdvPhyUGS,
+		IUCDataProfile12, IUCDataProfile13:
+		return true
+	}
+	return false
+}
+
+// isUnicastSID returns false for the null, multicast and broadcast SIDs.
+func isUnicastSID(sid uint16) bool {
+	return sid != 0 && sid < 0x3e00
+}
+
+// Add accounts the data grants of a decoded MAP of any version.
+func (accounting *MapAccounting) Add(m *DOCSISBaseMap) {
+	channel, ok := accounting.channels[m.UpstreamChannelID]
+	if !ok {
+		channel = &ChannelUsage{}
+		accounting.channels[m.UpstreamChannelID] = channel
+	}
+	channel.Maps++
+	channel.Minislots += uint64(m.MapLength())
+
+	for _, element := range m.Elements {
+		if !isDataGrant(element.IUC) || !isUnicastSID(element.SID) || element.Minislots == 0 {
+			continue
+		}
+
+		usage, ok := accounting.sids[element.SID]
+		if !ok {
+			usage = &SIDUsage{ByIUC: make(map[uint8]uint64)}
+			accounting.sids[element.SID] = usage
+		}
+		usage.Grants++
+		usage.Minislots += uint64(element.Minislots)
+		usage.ByIUC[element.IUC] += uint64(element.Minislots)
+	}
+}
+
+// Usage returns the grants of every SID seen so far.
+func (accounting *MapAccounting) Usage() map[uint16]SIDUsage {
+	result := make(map[uint16]SIDUsage, len(accounting.sids))
+	for sid, usage := range accounting.sids {
+		byIUC := make(map[uint8]uint64, len(usage.ByIUC))
+		for iuc, minislots := range usage.ByIUC {
+			byIUC[iuc] = minislots
+		}
+		result[sid] = SIDUsage{Grants: usage.Grants, Minislots: usage.Minislots, ByIUC: byIUC}
+	}
+	return result
+}
+
+// Channels returns the totals of every upstream channel seen so far.
+func (accounting *MapAccounting) Channels() map[uint8]ChannelUsage {
+	result := make(map[uint8]ChannelUsage, len(accounting.channels))
+	for channelID, usage := range accounting.channels {
+		result[channelID] = *usage
+	}
+	return result
+}
+
+// Share returns the fraction of all mapped minislots granted to the SID.
+func (accounting *MapAccounting) Share(sid uint16) float64 {
+	usage, ok := accounting.sids[sid]
+	if !ok {
+		return 0
+	}
+
+	var total uint64
+	for _, channel := range accounting.channels {
+		total += channel.Minislots
+	}
+	if total == 0 {
+		return 0
+	}
+
+	return float64(usage.Minislots) / float64(total)
+}
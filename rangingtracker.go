@@ -0,0 +1,139 @@
+package main
+
+import (
+	"net"
+	"time"
+)
+
+// RangingAnomaly flags unusual ranging responses.
+type RangingAnomaly uint8
+
+const (
+	// RangingAnomalyAbort is set if the CMTS aborted the ranging.
+	RangingAnomalyAbort RangingAnomaly = 1 << iota
+	// RangingAnomalyLargeTimingAdjust is set if the timing adjustment exceeds the threshold.
+	RangingAnomalyLargeTimingAdjust
+	// RangingAnomalyLargePowerAdjust is set if the power adjustment exceeds the threshold.
+	RangingAnomalyLargePowerAdjust
+	// RangingAnomalyRepeatedLargeAdjust is set if several consecutive responses had large adjustments.
+	RangingAnomalyRepeatedLargeAdjust
+)
+
+// RangingEntry is a single ranging response in the history of a modem.
+type RangingEntry struct {
+	Time                  time.Time
+	Sid                   uint16
+	UpstreamChannelID     uint8
+	TimingAdjust          int32
+	PowerLevelAdjust      int8
+	OffsetFrequencyAdjust int16
+	RangingStatus         uint8
+	Anomalies             RangingAnomaly
+}
+
+type rangingHistory struct {
+	entries           []RangingEntry
+	largeAdjustStreak int
+}
+
+// RangingTracker keeps the history of ranging adjustments per modem.
+type RangingTracker struct {
+	// TimingThreshold is the absolute timing adjustment considered large.
+	TimingThreshold int32
+	// PowerThreshold is the absolute power adjustment in 0.25 dB considered large.
+	PowerThreshold int8
+	// RepeatThreshold is the number of consecutive large adjustments that are flagged.
+	RepeatThreshold int
+	// MaxHistory limits the number of entries kept per modem.
+	MaxHistory int
+	// OnAnomaly is called for every entry with anomalies.
+	OnAnomaly func(mac net.HardwareAddr, entry RangingEntry)
+
+	modems map[string]*rangingHistory
+	sids   map[uint16]string
+}
+
+func newRangingTracker() *RangingTracker {
+	return &RangingTracker{
+		TimingThreshold: 64,
+		PowerThreshold:  12,
+		RepeatThreshold: 3,
+		MaxHistory:      100,
+		modems:          make(map[string]*rangingHistory),
+		sids:            make(map[uint16]string),
+	}
+}
+
+// Add records the ranging response sent to the modem.
+func (tracker *RangingTracker) Add(mac net.HardwareAddr, rsp *DOCSISRngRsp, captureTime time.Time) {
+	key := mac.String()
+	history, ok := tracker.modems[key]
+	if !ok {
+		history = &rangingHistory{}
+		tracker.modems[key] = history
+	}
+	tracker.sids[rsp.Sid] = key
+
+	entry := RangingEntry{
+		Time:                  captureTime,
+		Sid:                   rsp.Sid,
+		UpstreamChannelID:     rsp.UpstreamChannelID,
+		TimingAdjust:          rsp.TimingAdjust,
+		PowerLevelAdjust:      rsp.PowerLevelAdjust,
+		OffsetFrequencyAdjust: rsp.OffsetFrequencyAdjust,
+		RangingStatus:         rsp.RangingStatus,
+	}
+
+	if rsp.RangingStatus == RangingStatusAbort {
+		entry.Anomalies |= RangingAnomalyAbort
+	}
+	if abs32(rsp.TimingAdjust) > tracker.TimingThreshold {
+		entry.Anomalies |= RangingAnomalyLargeTimingAdjust
+	}
+	if abs32(int32(rsp.PowerLevelAdjust)) > int32(tracker.PowerThreshold) {
+		entry.Anomalies |= RangingAnomalyLargePowerAdjust
+	}
+
+	if entry.Anomalies&(RangingAnomalyLargeTimingAdjust|RangingAnomalyLargePowerAdjust) != 0 {
+		history.largeAdjustStreak++
+		if history.largeAdjustStreak >= tracker.RepeatThreshold {
+			entry.Anomalies |= RangingAnomalyRepeatedLargeAdjust
+		}
+	} else {
+		history.largeAdjustStreak = 0
+	}
+
+	history.entries = append(history.entries, entry)
+	if tracker.MaxHistory > 0 && len(history.entries) > tracker.MaxHistory {
+		history.entries = history.entries[len(history.entries)-tracker.MaxHistory:]
+	}
+
+	if entry.Anomalies != 0 && tracker.OnAnomaly != nil {
+		tracker.OnAnomaly(mac, entry)
+	}
+}
+
+// History returns the ranging history of a modem.
+func (tracker *RangingTracker) History(mac net.HardwareAddr) []RangingEntry {
+	history, ok := tracker.modems[mac.String()]
+	if !ok {
+		return nil
+	}
+	return append([]RangingEntry(nil), history.entries...)
+}
+
+// HistoryBySID returns the ranging history of the modem last seen with the SID.
+func (tracker *RangingTracker) HistoryBySID(sid uint16) []RangingEntry {
+	key, ok := tracker.sids[sid]
+	if !ok {
+		return nil
+	}
+	return append([]RangingEntry(nil), tracker.modems[key].entries...)
+}
+
+func abs32(value int32) int32 {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"net"
+	"time"
+)
+
+// RegistrationHandshake is a registration request with its response and acknowledgement.
+type RegistrationHandshake struct {
+	MAC          net.HardwareAddr
+	Sid          uint16
+	RequestTime  time.Time
+	ResponseTime time.Time
+	AckTime      time.Time
+	// Response is the confirmation code of the REG-RSP and AckResponse the one of the REG-ACK
+	Response    byte
+	AckResponse byte
+	// the TLVs of all fragments of each message
+	RequestTLVs  TLVs
+	ResponseTLVs TLVs
+	AckTLVs      TLVs
+	// Complete is set once the REG-ACK was seen
+	Complete bool
+
+	firstSeen time.Time
+}
+
+type registrationKey struct {
+	mac string
+	sid uint16
+}
+
+// RegistrationTracker correlates the messages of a registration handshake by modem MAC and SID.
+type RegistrationTracker struct {
+	// Timeout after which an incomplete handshake is reported by Expire.
+	Timeout time.Duration
+	// OnHandshake is called for completed and expired handshakes.
+	OnHandshake func(handshake RegistrationHandshake)
+
+	pending map[registrationKey]*RegistrationHandshake
+}
+
+func newRegistrationTracker() *RegistrationTracker {
+	return &RegistrationTracker{
+		Timeout: 30 * time.Second,
+		pending: make(map[registrationKey]*RegistrationHandshake),
+	}
+}
+
+func (tracker *RegistrationTracker) handshake(mac net.HardwareAddr, sid uint16, captureTime time.Time) *RegistrationHandshake {
+	key := registrationKey{mac: mac.String(), sid: sid}
+
+	handshake, ok := tracker.pending[key]
+	if !ok {
+		handshake = &RegistrationHandshake{
+			MAC:       append(net.HardwareAddr(nil), mac...),
+			Sid:       sid,
+			firstSeen: captureTime,
+		}
+		tracker.pending[key] = handshake
+	}
+	return handshake
+}
+
+// AddRequest records a REG-REQ or REG-REQ-MP fragment sent by the modem.
+// The fragment number is 0 for REG-REQ. A REG-REQ or first fragment is a new transmission
+// of the request and replaces the TLVs recorded so far, later fragments are added to them.
+func (tracker *RegistrationTracker) AddRequest(mac net.HardwareAddr, sid uint16, fragmentNumber uint8, encodings *DOCSISRegEncodings, captureTime time.Time) {
+	handshake := tracker.handshake(mac, sid, captureTime)
+	if handshake.RequestTime.IsZero() {
+		handshake.RequestTime = captureTime
+	}
+	if fragmentNumber <= 1 {
+		handshake.RequestTLVs = nil
+	}
+	handshake.RequestTLVs = append(handshake.RequestTLVs, encodings.TLVs.Clone()...)
+}
+
+// AddResponse records a REG-RSP or REG-RSP-MP fragment sent to the modem.
+// The fragment number is 0 for REG-RSP and is handled like the one of AddRequest.
+func (tracker *RegistrationTracker) AddResponse(mac net.HardwareAddr, rsp *DOCSISBaseRegRsp, fragmentNumber uint8, captureTime time.Time) {
+	handshake := tracker.handshake(mac, rsp.Sid, captureTime)
+	if handshake.ResponseTime.IsZero() {
+		handshake.ResponseTime = captureTime
+	}
+	handshake.Response = rsp.Response
+	if fragmentNumber <= 1 {
+		handshake.ResponseTLVs = nil
+	}
+	handshake.ResponseTLVs = append(handshake.ResponseTLVs, rsp.TLVs.Clone()...)
+}
+
+// AddAck records the REG-ACK sent by the modem which completes the handshake.
+func (tracker *RegistrationTracker) AddAck(mac net.HardwareAddr, ack *DOCSISRegAck, captureTime time.Time) {
+	handshake := tracker.handshake(mac, ack.Sid, captureTime)
+	handshake.AckTime = captureTime
+	handshake.AckResponse = ack.Response
+	handshake.AckTLVs = ack.TLVs.Clone()
+	handshake.Complete = true
+
+	delete(tracker.pending, registrationKey{mac: mac.String(), sid: ack.Sid})
+	if tracker.OnHandshake != nil {
+		tracker.OnHandshake(*handshake)
+	}
+}
+
+// Expire reports and removes the handshakes which didn't complete within the timeout.
+func (tracker *RegistrationTracker) Expire(now time.Time) {
+	for key, handshake := range tracker.pending {
+		if now.Sub(handshake.firstSeen) < tracker.Timeout {
+			continue
+		}
+
+		delete(tracker.pending, key)
+		if tracker.OnHandshake != nil {
+			tracker.OnHandshake(*handshake)
+		}
+	}
+}
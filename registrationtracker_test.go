@@ -0,0 +1,105 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRegistrationTrackerRetransmission(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	request := &DOCSISRegEncodings{TLVs: TLVs{uint8TLV(1, 1), uint8TLV(3, 1)}}
+
+	tracker := newRegistrationTracker()
+	var handshakes []RegistrationHandshake
+	tracker.OnHandshake = func(handshake RegistrationHandshake) {
+		handshakes = append(handshakes, handshake)
+	}
+
+	tracker.AddRequest(testModemMAC, 5, 0, request, start)
+	tracker.AddRequest(testModemMAC, 5, 0, request, start.Add(time.Second))
+
+	rsp := &DOCSISBaseRegRsp{Sid: 5}
+	tracker.AddResponse(testModemMAC, rsp, 0, start.Add(2*time.Second))
+	ack := &DOCSISRegAck{Sid: 5}
+	tracker.AddAck(testModemMAC, ack, start.Add(3*time.Second))
+
+	if len(handshakes) != 1 {
+		t.Fatalf("handshakes are %+v, want 1", handshakes)
+	}
+	handshake := handshakes[0]
+	if len(handshake.RequestTLVs) != 2 {
+		t.Errorf("request tlvs are %v, want the 2 tlvs of one request", handshake.RequestTLVs)
+	}
+	if !handshake.Complete || !handshake.RequestTime.Equal(start) {
+		t.Errorf("handshake complete %v with request time %v", handshake.Complete, handshake.RequestTime)
+	}
+}
+
+func TestRegistrationTrackerResponseRetransmission(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	rsp := &DOCSISBaseRegRsp{Sid: 5}
+	rsp.TLVs = TLVs{uint8TLV(1, 1), uint8TLV(3, 1)}
+	first := &DOCSISBaseRegRsp{Sid: 5}
+	first.TLVs = TLVs{uint8TLV(1, 1)}
+	second := &DOCSISBaseRegRsp{Sid: 5}
+	second.TLVs = TLVs{uint8TLV(3, 1), uint8TLV(18, 4)}
+
+	tracker := newRegistrationTracker()
+	var handshakes []RegistrationHandshake
+	tracker.OnHandshake = func(handshake RegistrationHandshake) {
+		handshakes = append(handshakes, handshake)
+	}
+
+	// the CMTS retransmits REG-RSP until the REG-ACK arrives
+	tracker.AddResponse(testModemMAC, rsp, 0, start)
+	tracker.AddResponse(testModemMAC, rsp, 0, start.Add(time.Second))
+	tracker.AddAck(testModemMAC, &DOCSISRegAck{Sid: 5}, start.Add(2*time.Second))
+
+	// REG-RSP-MP fragments accumulate, the retransmission of the first fragment starts over
+	tracker.AddResponse(testModemMAC, first, 1, start)
+	tracker.AddResponse(testModemMAC, second, 2, start)
+	tracker.AddResponse(testModemMAC, first, 1, start.Add(time.Second))
+	tracker.AddResponse(testModemMAC, second, 2, start.Add(time.Second))
+	tracker.AddAck(testModemMAC, &DOCSISRegAck{Sid: 5}, start.Add(2*time.Second))
+
+	if len(handshakes) != 2 {
+		t.Fatalf("handshakes are %+v, want 2", handshakes)
+	}
+	if tlvs := handshakes[0].ResponseTLVs; len(tlvs) != 2 {
+		t.Errorf("response tlvs are %v, want the 2 tlvs of one response", tlvs)
+	}
+	if !handshakes[0].ResponseTime.Equal(start) {
+		t.Errorf("response time is %v, want the first transmission", handshakes[0].ResponseTime)
+	}
+	tlvs := handshakes[1].ResponseTLVs
+	if len(tlvs) != 3 || tlvs[0].Type != 1 || tlvs[1].Type != 3 || tlvs[2].Type != 18 {
+		t.Errorf("response tlvs are %v, want the tlvs of both fragments once", tlvs)
+	}
+}
+
+func TestRegistrationTrackerMultipartRetransmission(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	first := &DOCSISRegEncodings{TLVs: TLVs{uint8TLV(1, 1)}}
+	second := &DOCSISRegEncodings{TLVs: TLVs{uint8TLV(3, 1), uint8TLV(18, 4)}}
+
+	tracker := newRegistrationTracker()
+	var handshakes []RegistrationHandshake
+	tracker.OnHandshake = func(handshake RegistrationHandshake) {
+		handshakes = append(handshakes, handshake)
+	}
+
+	// the fragments accumulate, the retransmission of the first fragment starts over
+	tracker.AddRequest(testModemMAC, 5, 1, first, start)
+	tracker.AddRequest(testModemMAC, 5, 2, second, start)
+	tracker.AddRequest(testModemMAC, 5, 1, first, start.Add(time.Second))
+	tracker.AddRequest(testModemMAC, 5, 2, second, start.Add(time.Second))
+	tracker.AddAck(testModemMAC, &DOCSISRegAck{Sid: 5}, start.Add(2*time.Second))
+
+	if len(handshakes) != 1 {
+		t.Fatalf("handshakes are %+v, want 1", handshakes)
+	}
+	tlvs := handshakes[0].RequestTLVs
+	if len(tlvs) != 3 || tlvs[0].Type != 1 || tlvs[1].Type != 3 || tlvs[2].Type != 18 {
+		t.Errorf("request tlvs are %v, want the tlvs of both fragments once", tlvs)
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/google/gopacket"
+)
+
+// ResequencerStats are the sequencing statistics of a single DSID.
+type ResequencerStats struct {
+	Delivered uint64
+	Lost      uint64
+	Late      uint64
+}
+
+type heldPacket struct {
+	data    []byte
+	arrival time.Time
+}
+
+type dsidStream struct {
+	next                uint16
+	sequenceChangeCount uint8
+	held                map[uint16]heldPacket
+	stats               ResequencerStats
+}
+
+// Resequencer restores the order of bonded downstream packets received through multiple tuners.
+// Packets are grouped by their DSID and ordered by the packet sequence number of the
+// Downstream Service extended header. Packets without a sequence number are passed through.
+type Resequencer struct {
+	// Window is how long packets are held back while waiting for a missing sequence number.
+	Window time.Duration
+	// OnLost is called with the first sequence number and the count of skipped packets.
+	OnLost func(dsid uint32, first uint16, count uint16)
+	// OnLate is called for packets that arrived after their sequence number was skipped.
+	OnLate func(dsid uint32, sequenceNumber uint16)
+
+	fn      processPacket
+	mu      sync.Mutex
+	docsis  DOCSIS
+	streams map[uint32]*dsidStream
+}
+
+func newResequencer(window time.Duration, fn processPacket) *Resequencer {
+	return &Resequencer{
+		Window:  window,
+		fn:      fn,
+		streams: make(map[uint32]*dsidStream),
+	}
+}
+
+// Process adds a packet to the resequencer. It is safe to call it from multiple goroutines.
+func (r *Resequencer) Process(packet []byte) {
+	r.processAt(packet, time.Now())
+}
+
+func (r *Resequencer) processAt(packet []byte, now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	err := r.docsis.DecodeFromBytes(packet, gopacket.NilDecodeFeedback)
+	if err != nil || !r.docsis.PacketSequenceNumberPresent {
+		r.fn(packet)
+		return
+	}
+
+	dsid := r.docsis.DSID
+	sequenceNumber := r.docsis.PacketSequenceNumber
+	sequenceChangeCount := r.docsis.SequenceChangeCount
+
+	stream, ok := r.streams[dsid]
+	if !ok {
+		stream = &dsidStream{
+			next:                sequenceNumber,
+			sequenceChangeCount: sequenceChangeCount,
+			held:                make(map[uint16]heldPacket),
+		}
+		r.streams[dsid] = stream
+	} else if stream.sequenceChangeCount != sequenceChangeCount {
+		// the CMTS restarted the sequence numbers
+		r.release(dsid, stream)
+		stream.next = sequenceNumber
+		stream.sequenceChangeCount = sequenceChangeCount
+	}
+
+	_, duplicate := stream.held[sequenceNumber]
+	if duplicate || int16(sequenceNumber-stream.next) < 0 {
+		stream.stats.Late++
+		if r.OnLate != nil {
+			r.OnLate(dsid, sequenceNumber)
+		}
+		return
+	}
+
+	// the buffer is reused by the caller so we need a copy
+	stream.held[sequenceNumber] = heldPacket{data: append([]byte(nil), packet...), arrival: now}
+	r.deliver(stream)
+	r.expire(now)
+}
+
+// deliver passes on all packets which are in sequence.
+func (r *Resequencer) deliver(stream *dsidStream) {
+	for {
+		held, ok := stream.held[stream.next]
+		if !ok {
+			return
+		}
+
+		delete(stream.held, stream.next)
+		stream.next++
+		stream.stats.Delivered++
+		r.fn(held.data)
+	}
+}
+
+// skip gives up on the missing sequence numbers before the next held packet.
+func (r *Resequencer) skip(dsid uint32, stream *dsidStream) {
+	first := true
+	var nearest uint16
+	for sequenceNumber := range stream.held {
+		if first || uint16(sequenceNumber-stream.next) < uint16(nearest-stream.next) {
+			nearest = sequenceNumber
+			first = false
+		}
+	}
+
+	count := nearest - stream.next
+	stream.stats.Lost += uint64(count)
+	if r.OnLost != nil && count > 0 {
+		r.OnLost(dsid, stream.next, count)
+	}
+	stream.next = nearest
+}
+
+// release delivers all held packets of a stream regardless of gaps.
+func (r *Resequencer) release(dsid uint32, stream *dsidStream) {
+	for len(stream.held) > 0 {
+		r.skip(dsid, stream)
+		r.deliver(stream)
+	}
+}
+
+// expire skips missing sequence numbers once a held packet is older than the window.
+func (r *Resequencer) expire(now time.Time) {
+	for dsid, stream := range r.streams {
+		for stream.hasExpired(now.Add(-r.Window)) {
+			r.skip(dsid, stream)
+			r.deliver(stream)
+		}
+	}
+}
+
+func (stream *dsidStream) hasExpired(deadline time.Time) bool {
+	for _, held := range stream.held {
+		if held.arrival.Before(deadline) {
+			return true
+		}
+	}
+	return false
+}
+
+// Flush delivers held packets whose window has passed. If all is set every held packet is delivered.
+func (r *Resequencer) Flush(all bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !all {
+		r.expire(time.Now())
+		return
+	}
+
+	for dsid, stream := range r.streams {
+		r.release(dsid, stream)
+	}
+}
+
+// Stats returns the statistics of every DSID seen so far.
+func (r *Resequencer) Stats() map[uint32]ResequencerStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats := make(map[uint32]ResequencerStats, len(r.streams))
+	for dsid, stream := range r.streams {
+		stats[dsid] = stream.stats
+	}
+	return stats
+}
+
+// ReadLoop reads TS packets from all readers concurrently and feeds them into the resequencer.
+func (r *Resequencer) ReadLoop(ctx context.Context, readers ...io.Reader) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errs := make(chan error, len(readers))
+	for _, reader := range readers {
+		go func(reader io.Reader) {
+			errs <- readPacketLoop(ctx, reader, r.Process)
+		}(reader)
+	}
+
+	interval := r.Window
+	if interval < 10*time.Millisecond {
+		interval = 10 * time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var err error
+	for remaining := len(readers); remaining > 0; {
+		select {
+		case readErr := <-errs:
+			remaining--
+			if readErr != nil && readErr != io.EOF && err == nil {
+				// stop the other readers as well
+				err = readErr
+				cancel()
+			}
+		case <-ticker.C:
+			r.Flush(false)
+		}
+	}
+
+	r.Flush(true)
+	return err
+}
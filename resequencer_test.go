@@ -0,0 +1,100 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func buildSequencedFrame(dsid uint32, sequenceNumber uint16) []byte {
+	ehdr := []byte{0x85, byte(dsid>>16) & 0x0f, byte(dsid >> 8), byte(dsid), byte(sequenceNumber >> 8), byte(sequenceNumber)}
+	return buildDOCSISFrame(0x01, ehdr, withEthernetCRC(make([]byte, 60)))
+}
+
+type resequencerRecorder struct {
+	t         *testing.T
+	docsis    DOCSIS
+	delivered []uint16
+	lost      [][2]uint16
+	late      []uint16
+}
+
+func newRecordingResequencer(t *testing.T, window time.Duration) (*Resequencer, *resequencerRecorder) {
+	recorder := &resequencerRecorder{t: t}
+	resequencer := newResequencer(window, func(packet []byte) {
+		if err := recorder.docsis.DecodeFromBytes(packet, nil); err != nil {
+			t.Fatal(err)
+		}
+		recorder.delivered = append(recorder.delivered, recorder.docsis.PacketSequenceNumber)
+	})
+	resequencer.OnLost = func(dsid uint32, first uint16, count uint16) {
+		recorder.lost = append(recorder.lost, [2]uint16{first, count})
+	}
+	resequencer.OnLate = func(dsid uint32, sequenceNumber uint16) {
+		recorder.late = append(recorder.late, sequenceNumber)
+	}
+	return resequencer, recorder
+}
+
+func TestResequencerReorders(t *testing.T) {
+	resequencer, recorder := newRecordingResequencer(t, time.Second)
+	now := time.Now()
+
+	for _, sequenceNumber := range []uint16{10, 12, 11, 14, 13} {
+		resequencer.processAt(buildSequencedFrame(0x123, sequenceNumber), now)
+	}
+
+	if want := []uint16{10, 11, 12, 13, 14}; !reflect.DeepEqual(recorder.delivered, want) {
+		t.Errorf("delivered %v, want %v", recorder.delivered, want)
+	}
+	if len(recorder.lost) != 0 || len(recorder.late) != 0 {
+		t.Errorf("lost %v, late %v", recorder.lost, recorder.late)
+	}
+}
+
+func TestResequencerGap(t *testing.T) {
+	resequencer, recorder := newRecordingResequencer(t, time.Second)
+	now := time.Now()
+
+	resequencer.processAt(buildSequencedFrame(0x123, 1), now)
+	resequencer.processAt(buildSequencedFrame(0x123, 4), now)
+	resequencer.processAt(buildSequencedFrame(0x123, 5), now)
+	if want := []uint16{1}; !reflect.DeepEqual(recorder.delivered, want) {
+		t.Fatalf("delivered %v before the window passed, want %v", recorder.delivered, want)
+	}
+
+	// the window has passed, 2 and 3 are given up
+	resequencer.processAt(buildSequencedFrame(0x123, 6), now.Add(2*time.Second))
+	if want := []uint16{1, 4, 5, 6}; !reflect.DeepEqual(recorder.delivered, want) {
+		t.Errorf("delivered %v, want %v", recorder.delivered, want)
+	}
+	if want := [][2]uint16{{2, 2}}; !reflect.DeepEqual(recorder.lost, want) {
+		t.Errorf("lost %v, want %v", recorder.lost, want)
+	}
+
+	resequencer.processAt(buildSequencedFrame(0x123, 3), now.Add(2*time.Second))
+	if want := []uint16{3}; !reflect.DeepEqual(recorder.late, want) {
+		t.Errorf("late %v, want %v", recorder.late, want)
+	}
+
+	stats := resequencer.Stats()[0x123]
+	if stats.Delivered != 4 || stats.Lost != 2 || stats.Late != 1 {
+		t.Errorf("stats %+v", stats)
+	}
+}
+
+func TestResequencerWrap(t *testing.T) {
+	resequencer, recorder := newRecordingResequencer(t, time.Second)
+	now := time.Now()
+
+	for _, sequenceNumber := range []uint16{65534, 0, 65535, 1} {
+		resequencer.processAt(buildSequencedFrame(0x123, sequenceNumber), now)
+	}
+
+	if want := []uint16{65534, 65535, 0, 1}; !reflect.DeepEqual(recorder.delivered, want) {
+		t.Errorf("delivered %v, want %v", recorder.delivered, want)
+	}
+	if len(recorder.lost) != 0 || len(recorder.late) != 0 {
+		t.Errorf("lost %v, late %v", recorder.lost, recorder.late)
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// ServiceFlow are the decoded upstream (type 24) or downstream (type 25) service flow encodings.
+type ServiceFlow struct {
+	Upstream            bool
+	Reference           uint16
+	SFID                uint32
+	SID                 uint16
+	ServiceClassName    string
+	QoSParameterSetType uint8
+	TrafficPriority     uint8
+	MaxSustainedRate    uint32
+	MaxTrafficBurst     uint32
+	MinReservedRate     uint32
+	TLVs                TLVs
+}
+
+// parseServiceFlows decodes all service flow encodings of the TLVs.
+func parseServiceFlows(tlvs TLVs) ([]ServiceFlow, error) {
+	var flows []ServiceFlow
+
+	for _, tlv := range tlvs {
+		if tlv.Type != 24 && tlv.Type != 25 {
+			continue
+		}
+
+		flow := ServiceFlow{Upstream: tlv.Type == 24, TLVs: tlv.Children}
+
+		var err error
+		for _, inner := range tlv.Children {
+			switch inner.Type {
+			case 1:
+				flow.Reference, err = inner.Uint16()
+			case 2:
+				flow.SFID, err = inner.Uint32()
+			case 3:
+				flow.SID, err = inner.Uint16()
+			case 4:
+				// the service class name is zero terminated
+				flow.ServiceClassName = string(bytes.TrimRight(inner.Value, "\x00"))
+			case 6:
+				flow.QoSParameterSetType, err = inner.Uint8()
+			case 7:
+				flow.TrafficPriority, err = inner.Uint8()
+			case 8:
+				flow.MaxSustainedRate, err = inner.Uint32()
+			case 9:
+				flow.MaxTrafficBurst, err = inner.Uint32()
+			case 10:
+				flow.MinReservedRate, err = inner.Uint32()
+			}
+
+			if err != nil {
+				return nil, fmt.Errorf("service flow: %w", err)
+			}
+		}
+
+		flows = append(flows, flow)
+	}
+
+	return flows, nil
+}
+
+// update overwrites the parameters which are set in other.
+func (flow *ServiceFlow) update(other ServiceFlow) {
+	if other.Reference != 0 {
+		flow.Reference = other.Reference
+	}
+	if other.SFID != 0 {
+		flow.SFID = other.SFID
+	}
+	if other.SID != 0 {
+		flow.SID = other.SID
+	}
+	if other.ServiceClassName != "" {
+		flow.ServiceClassName = other.ServiceClassName
+	}
+	if other.QoSParameterSetType != 0 {
+		flow.QoSParameterSetType = other.QoSParameterSetType
+	}
+	if other.TrafficPriority != 0 {
+		flow.TrafficPriority = other.TrafficPriority
+	}
+	if other.MaxSustainedRate != 0 {
+		flow.MaxSustainedRate = other.MaxSustainedRate
+	}
+	if other.MaxTrafficBurst != 0 {
+		flow.MaxTrafficBurst = other.MaxTrafficBurst
+	}
+	if other.MinReservedRate != 0 {
+		flow.MinReservedRate = other.MinReservedRate
+	}
+	if other.TLVs != nil {
+		flow.TLVs = other.TLVs
+	}
+}
+
+// clone returns a deep copy that doesn't reference the decoded packet data anymore.
+func (flow ServiceFlow) clone() ServiceFlow {
+	flow.TLVs = flow.TLVs.Clone()
+	return flow
+}
+
+// encode returns the service flow encoding (type 24 or 25) for the parameters.
+// TLVs which aren't decoded into fields are carried over from flow.TLVs.
+func (flow ServiceFlow) encode() TLV {
+	tlv := TLV{Type: 25}
+	if flow.Upstream {
+		tlv.Type = 24
+	}
+
+	if flow.Reference != 0 {
+		tlv.Children = append(tlv.Children, uint16TLV(1, flow.Reference))
+	}
+	if flow.SFID != 0 {
+		tlv.Children = append(tlv.Children, uint32TLV(2, flow.SFID))
+	}
+	if flow.SID != 0 {
+		tlv.Children = append(tlv.Children, uint16TLV(3, flow.SID))
+	}
+	if flow.ServiceClassName != "" {
+		tlv.Children = append(tlv.Children, TLV{Type: 4, Value: append([]byte(flow.ServiceClassName), 0)})
+	}
+	if flow.QoSParameterSetType != 0 {
+		tlv.Children = append(tlv.Children, uint8TLV(6, flow.QoSParameterSetType))
+	}
+	if flow.TrafficPriority != 0 {
+		tlv.Children = append(tlv.Children, uint8TLV(7, flow.TrafficPriority))
+	}
+	if flow.MaxSustainedRate != 0 {
+		tlv.Children = append(tlv.Children, uint32TLV(8, flow.MaxSustainedRate))
+	}
+	if flow.MaxTrafficBurst != 0 {
+		tlv.Children = append(tlv.Children, uint32TLV(9, flow.MaxTrafficBurst))
+	}
+	if flow.MinReservedRate != 0 {
+		tlv.Children = append(tlv.Children, uint32TLV(10, flow.MinReservedRate))
+	}
+
+	for _, inner := range flow.TLVs {
+		switch inner.Type {
+		case 1, 2, 3, 4, 6, 7, 8, 9, 10:
+			continue
+		}
+		tlv.Children = append(tlv.Children, inner)
+	}
+
+	return tlv
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"net"
+	"sort"
+)
+
+// DynamicServiceKind is the kind of a dynamic service transaction.
+type DynamicServiceKind uint8
+
+const (
+	// DynamicServiceAdd adds service flows (DSA)
+	DynamicServiceAdd DynamicServiceKind = iota
+	// DynamicServiceChange changes service flows (DSC)
+	DynamicServiceChange
+	// DynamicServiceDelete deletes service flows (DSD)
+	DynamicServiceDelete
+)
+
+type dynamicServiceKey struct {
+	mac           string
+	transactionID uint16
+}
+
+type dynamicServiceTransaction struct {
+	kind  DynamicServiceKind
+	flows []ServiceFlow
+}
+
+// ServiceFlowTracker keeps the current set of service flows per modem.
+// The flows of the REG-RSP are the initial set, confirmed dynamic service transactions are applied on top.
+type ServiceFlowTracker struct {
+	// OnChange is called for every flow added, changed or deleted.
+	OnChange func(mac net.HardwareAddr, kind DynamicServiceKind, flow ServiceFlow)
+
+	modems  map[string]map[uint32]ServiceFlow
+	pending map[dynamicServiceKey]*dynamicServiceTransaction
+}
+
+func newServiceFlowTracker() *ServiceFlowTracker {
+	return &ServiceFlowTracker{
+		modems:  make(map[string]map[uint32]ServiceFlow),
+		pending: make(map[dynamicServiceKey]*dynamicServiceTransaction),
+	}
+}
+
+// SetRegistration replaces the flows of the modem with the ones from its registration response.
+func (tracker *ServiceFlowTracker) SetRegistration(mac net.HardwareAddr, encodings *DOCSISRegEncodings) {
+	flows := make(map[uint32]ServiceFlow)
+	for _, flow := range encodings.ServiceFlows {
+		if flow.SFID != 0 {
+			flows[flow.SFID] = flow.clone()
+		}
+	}
+	tracker.modems[mac.String()] = flows
+}
+
+// AddRequest records a DSA-REQ, DSC-REQ or DSD-REQ exchanged with the modem.
+func (tracker *ServiceFlowTracker) AddRequest(mac net.HardwareAddr, kind DynamicServiceKind, request *DOCSISBaseDynamicService) {
+	transaction := &dynamicServiceTransaction{kind: kind}
+	for _, flow := range request.ServiceFlows {
+		transaction.flows = append(transaction.flows, flow.clone())
+	}
+	// a DSD-REQ names the deleted flow in its header instead of service flow encodings
+	if kind == DynamicServiceDelete && request.SFID != 0 {
+		transaction.flows = append(transaction.flows, ServiceFlow{SFID: request.SFID})
+	}
+	tracker.pending[dynamicServiceKey{mac: mac.String(), transactionID: request.TransactionID}] = transaction
+}
+
+// AddConfirmation records a response or acknowledgement exchanged with the modem.
+// A successful confirmation applies the transaction to the flows of the modem.
+func (tracker *ServiceFlowTracker) AddConfirmation(mac net.HardwareAddr, kind DynamicServiceKind, confirmation *DOCSISBaseDynamicService) {
+	key := dynamicServiceKey{mac: mac.String(), transactionID: confirmation.TransactionID}
+	transaction, ok := tracker.pending[key]
+	if !ok {
+		// the request wasn't captured, the confirmation is all we know
+		transaction = &dynamicServiceTransaction{kind: kind}
+	}
+	delete(tracker.pending, key)
+
+	if confirmation.ConfirmationCode != 0 {
+		return
+	}
+
+	// the confirmation contains the SFIDs assigned to the requested flows
+	flows := transaction.flows
+	for _, confirmed := range confirmation.ServiceFlows {
+		matched := false
+		for i := range flows {
+			if (confirmed.Reference != 0 && flows[i].Reference == confirmed.Reference) ||
+				(confirmed.SFID != 0 && flows[i].SFID == confirmed.SFID) {
+				flows[i].update(confirmed.clone())
+				matched = true
+			}
+		}
+		if !matched {
+			flows = append(flows, confirmed.clone())
+		}
+	}
+
+	tracker.apply(mac, transaction.kind, flows)
+}
+
+func (tracker *ServiceFlowTracker) apply(mac net.HardwareAddr, kind DynamicServiceKind, flows []ServiceFlow) {
+	current, ok := tracker.modems[mac.String()]
+	if !ok {
+		current = make(map[uint32]ServiceFlow)
+		tracker.modems[mac.String()] = current
+	}
+
+	for _, flow := range flows {
+		if flow.SFID == 0 {
+			continue
+		}
+
+		switch kind {
+		case DynamicServiceAdd:
+			current[flow.SFID] = flow
+		case DynamicServiceChange:
+			if existing, ok := current[flow.SFID]; ok {
+				existing.update(flow)
+				flow = existing
+			}
+			current[flow.SFID] = flow
+		case DynamicServiceDelete:
+			if existing, ok := current[flow.SFID]; ok {
+				flow = existing
+			}
+			delete(current, flow.SFID)
+		}
+
+		if tracker.OnChange != nil {
+			tracker.OnChange(mac, kind, flow)
+		}
+	}
+}
+
+// Flows returns the current service flows of the modem ordered by SFID.
+func (tracker *ServiceFlowTracker) Flows(mac net.HardwareAddr) []ServiceFlow {
+	current := tracker.modems[mac.String()]
+
+	flows := make([]ServiceFlow, 0, len(current))
+	for _, flow := range current {
+		flows = append(flows, flow)
+	}
+	sort.Slice(flows, func(i, j int) bool {
+		return flows[i].SFID < flows[j].SFID
+	})
+
+	return flows
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"time"
+)
+
+// cmtsClockRate is the nominal rate of the CMTS master clock in Hz.
+const cmtsClockRate = 10.24e6
+
+// SyncStats are the SYNC statistics of a single downstream.
+type SyncStats struct {
+	Count          int
+	FirstTimestamp uint32
+	LastTimestamp  uint32
+	FirstSeen      time.Time
+	LastSeen       time.Time
+	// ClockRate is the estimated rate of the CMTS clock in Hz based on the capture time.
+	ClockRate float64
+	// DriftPPM is the deviation of ClockRate from the nominal 10.24 MHz.
+	DriftPPM     float64
+	MeanInterval time.Duration
+	MinInterval  time.Duration
+	MaxInterval  time.Duration
+}
+
+type syncState struct {
+	stats SyncStats
+	// ticks is the number of clock ticks since the first SYNC with timestamp wraparounds resolved
+	ticks uint64
+}
+
+// SyncTracker correlates SYNC timestamps with the capture time per downstream.
+type SyncTracker struct {
+	downstreams map[string]*syncState
+}
+
+func newSyncTracker() *SyncTracker {
+	return &SyncTracker{downstreams: make(map[string]*syncState)}
+}
+
+// Add records a SYNC message received on the downstream at the capture time.
+func (tracker *SyncTracker) Add(downstream string, timestamp uint32, captureTime time.Time) {
+	state, ok := tracker.downstreams[downstream]
+	if !ok {
+		tracker.downstreams[downstream] = &syncState{stats: SyncStats{
+			Count:          1,
+			FirstTimestamp: timestamp,
+			LastTimestamp:  timestamp,
+			FirstSeen:      captureTime,
+			LastSeen:       captureTime,
+		}}
+		return
+	}
+
+	stats := &state.stats
+	interval := captureTime.Sub(stats.LastSeen)
+	// the 32 bit timestamp wraps around roughly every 7 minutes which is much longer than the SYNC interval
+	state.ticks += uint64(timestamp - stats.LastTimestamp)
+
+	stats.Count++
+	stats.LastTimestamp = timestamp
+	stats.LastSeen = captureTime
+
+	if stats.MinInterval == 0 || interval < stats.MinInterval {
+		stats.MinInterval = interval
+	}
+	if interval > stats.MaxInterval {
+		stats.MaxInterval = interval
+	}
+
+	elapsed := stats.LastSeen.Sub(stats.FirstSeen)
+	stats.MeanInterval = elapsed / time.Duration(stats.Count-1)
+	if elapsed > 0 {
+		stats.ClockRate = float64(state.ticks) / elapsed.Seconds()
+		stats.DriftPPM = (stats.ClockRate - cmtsClockRate) / cmtsClockRate * 1e6
+	}
+}
+
+// Stats returns the statistics of a downstream.
+func (tracker *SyncTracker) Stats(downstream string) (SyncStats, bool) {
+	state, ok := tracker.downstreams[downstream]
+	if !ok {
+		return SyncStats{}, false
+	}
+	return state.stats, true
+}
+
+// AllStats returns the statistics of all downstreams.
+func (tracker *SyncTracker) AllStats() map[string]SyncStats {
+	result := make(map[string]SyncStats, len(tracker.downstreams))
+	for downstream, state := range tracker.downstreams {
+		result[downstream] = state.stats
+	}
+	return result
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"net"
+	"time"
+)
+
+// TEKAnomaly flags unusual key replies.
+type TEKAnomaly uint8
+
+const (
+	// TEKAnomalyNonMonotonicSequence is set if the key sequence number didn't stay or advance by one.
+	TEKAnomalyNonMonotonicSequence TEKAnomaly = 1 << iota
+	// TEKAnomalyLongLifetime is set if a remaining lifetime exceeds MaxLifetime.
+	TEKAnomalyLongLifetime
+)
+
+// TEKEntry is a single Key Reply in the TEK timeline of a security association.
+type TEKEntry struct {
+	Time time.Time
+	// the older and the newer TEK generation of the reply
+	OlderSequenceNumber uint8
+	OlderLifetime       time.Duration
+	NewerSequenceNumber uint8
+	NewerLifetime       time.Duration
+	// Rollover is set if the newer TEK wasn't seen before
+	Rollover bool
+	// NextRekey is when the older TEK expires and the modem has to switch to the newer one
+	NextRekey time.Time
+	Anomalies TEKAnomaly
+}
+
+type tekKey struct {
+	mac  string
+	said uint16
+}
+
+type tekTimeline struct {
+	entries []TEKEntry
+}
+
+// TEKTracker builds the timeline of TEK rollovers per modem and SAID from Key Replies.
+type TEKTracker struct {
+	// MaxLifetime is the remaining lifetime considered unusually long.
+	MaxLifetime time.Duration
+	// MaxHistory limits the number of entries kept per security association.
+	MaxHistory int
+	// OnRollover is called when a new TEK generation is seen.
+	OnRollover func(mac net.HardwareAddr, said uint16, entry TEKEntry)
+	// OnAnomaly is called for every entry with anomalies.
+	OnAnomaly func(mac net.HardwareAddr, said uint16, entry TEKEntry)
+
+	timelines map[tekKey]*tekTimeline
+}
+
+func newTEKTracker() *TEKTracker {
+	return &TEKTracker{
+		// the longest TEK lifetime allowed by BPI+
+		MaxLifetime: 7 * 24 * time.Hour,
+		MaxHistory:  100,
+		timelines:   make(map[tekKey]*tekTimeline),
+	}
+}
+
+// Add records a BPKM-RSP sent to the modem. Only Key Replies with TEK parameters are used.
+func (tracker *TEKTracker) Add(mac net.HardwareAddr, rsp *DOCSISBpkmRsp, captureTime time.Time) {
+	if rsp.Code != DocsisBpkmCodeKeyReply || len(rsp.TEKParameters) == 0 {
+		return
+	}
+
+	key := tekKey{mac: mac.String(), said: rsp.SAID}
+	timeline, ok := tracker.timelines[key]
+	if !ok {
+		timeline = &tekTimeline{}
+		tracker.timelines[key] = timeline
+	}
+
+	// the older generation is sent first
+	older := rsp.TEKParameters[0]
+	newer := rsp.TEKParameters[len(rsp.TEKParameters)-1]
+
+	entry := TEKEntry{
+		Time:                captureTime,
+		OlderSequenceNumber: older.KeySequenceNumber,
+		OlderLifetime:       time.Duration(older.KeyLifetime) * time.Second,
+		NewerSequenceNumber: newer.KeySequenceNumber,
+		NewerLifetime:       time.Duration(newer.KeyLifetime) * time.Second,
+	}
+	entry.NextRekey = captureTime.Add(entry.OlderLifetime)
+
+	if len(timeline.entries) == 0 {
+		entry.Rollover = true
+	} else {
+		previous := timeline.entries[len(timeline.entries)-1]
+		// key sequence numbers are 4 bits and wrap around
+		advance := (entry.NewerSequenceNumber - previous.NewerSequenceNumber) & 0x0f
+		if advance == 1 {
+			entry.Rollover = true
+		} else if advance != 0 {
+			entry.Anomalies |= TEKAnomalyNonMonotonicSequence
+		}
+	}
+
+	if entry.OlderLifetime > tracker.MaxLifetime || entry.NewerLifetime > tracker.MaxLifetime {
+		entry.Anomalies |= TEKAnomalyLongLifetime
+	}
+
+	timeline.entries = append(timeline.entries, entry)
+	if tracker.MaxHistory > 0 && len(timeline.entries) > tracker.MaxHistory {
+		timeline.entries = timeline.entries[len(timeline.entries)-tracker.MaxHistory:]
+	}
+
+	if entry.Rollover && tracker.OnRollover != nil {
+		tracker.OnRollover(mac, rsp.SAID, entry)
+	}
+	if entry.Anomalies != 0 && tracker.OnAnomaly != nil {
+		tracker.OnAnomaly(mac, rsp.SAID, entry)
+	}
+}
+
+// Timeline returns the TEK timeline of a security association of the modem.
+func (tracker *TEKTracker) Timeline(mac net.HardwareAddr, said uint16) []TEKEntry {
+	timeline, ok := tracker.timelines[tekKey{mac: mac.String(), said: said}]
+	if !ok {
+		return nil
+	}
+	return append([]TEKEntry(nil), timeline.entries...)
+}
+
+// NextRekey returns the predicted time of the next rekey of a security association of the modem.
+func (tracker *TEKTracker) NextRekey(mac net.HardwareAddr, said uint16) (time.Time, bool) {
+	timeline, ok := tracker.timelines[tekKey{mac: mac.String(), said: said}]
+	if !ok || len(timeline.entries) == 0 {
+		return time.Time{}, false
+	}
+	return timeline.entries[len(timeline.entries)-1].NextRekey, true
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+// keyReply returns a Key Reply with the older and newer TEK generation.
+func keyReply(said uint16, older uint8, newer uint8, lifetime uint32) *DOCSISBpkmRsp {
+	rsp := &DOCSISBpkmRsp{}
+	rsp.Code = DocsisBpkmCodeKeyReply
+	rsp.SAID = said
+	rsp.TEKParameters = []TEKParameters{
+		{KeySequenceNumber: older, KeyLifetime: lifetime / 2},
+		{KeySequenceNumber: newer, KeyLifetime: lifetime},
+	}
+	return rsp
+}
+
+func TestTEKTrackerRollover(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tracker := newTEKTracker()
+	var rollovers []TEKEntry
+	tracker.OnRollover = func(mac net.HardwareAddr, said uint16, entry TEKEntry) {
+		rollovers = append(rollovers, entry)
+	}
+	tracker.OnAnomaly = func(mac net.HardwareAddr, said uint16, entry TEKEntry) {
+		t.Errorf("anomaly %d for sequence %d", entry.Anomalies, entry.NewerSequenceNumber)
+	}
+
+	tracker.Add(testModemMAC, keyReply(0x2001, 13, 14, 3600), start)
+	// a repeated reply doesn't roll over
+	tracker.Add(testModemMAC, keyReply(0x2001, 13, 14, 3000), start.Add(10*time.Minute))
+	tracker.Add(testModemMAC, keyReply(0x2001, 14, 15, 3600), start.Add(time.Hour))
+	// the 4 bit sequence number wraps from 15 to 0
+	tracker.Add(testModemMAC, keyReply(0x2001, 15, 0, 3600), start.Add(2*time.Hour))
+
+	if len(rollovers) != 3 {
+		t.Fatalf("rollovers are %+v, want 3", rollovers)
+	}
+	if rollovers[1].NewerSequenceNumber != 15 || rollovers[2].NewerSequenceNumber != 0 {
+		t.Errorf("rollovers to sequence %d and %d, want 15 and 0", rollovers[1].NewerSequenceNumber, rollovers[2].NewerSequenceNumber)
+	}
+
+	if timeline := tracker.Timeline(testModemMAC, 0x2001); len(timeline) != 4 || timeline[1].Rollover {
+		t.Errorf("timeline is %+v", timeline)
+	}
+	if next, ok := tracker.NextRekey(testModemMAC, 0x2001); !ok || !next.Equal(start.Add(2*time.Hour+30*time.Minute)) {
+		t.Errorf("next rekey is %v, want %v", next, start.Add(2*time.Hour+30*time.Minute))
+	}
+	if _, ok := tracker.NextRekey(testModemMAC, 0x2002); ok {
+		t.Error("next rekey predicted for an unknown SAID")
+	}
+}
+
+func TestTEKTrackerAnomalies(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tracker := newTEKTracker()
+	var anomalies []TEKEntry
+	tracker.OnAnomaly = func(mac net.HardwareAddr, said uint16, entry TEKEntry) {
+		anomalies = append(anomalies, entry)
+	}
+
+	tracker.Add(testModemMAC, keyReply(0x2001, 2, 3, 3600), start)
+	// sequence number 4 was skipped
+	tracker.Add(testModemMAC, keyReply(0x2001, 4, 5, 3600), start.Add(time.Hour))
+	tracker.Add(testModemMAC, keyReply(0x2001, 5, 6, uint32((tracker.MaxLifetime+time.Hour)/time.Second)), start.Add(2*time.Hour))
+
+	if len(anomalies) != 2 {
+		t.Fatalf("anomalies are %+v, want 2", anomalies)
+	}
+	if anomalies[0].Anomalies != TEKAnomalyNonMonotonicSequence || anomalies[0].Rollover {
+		t.Errorf("skipped sequence has anomalies %d and rollover %v", anomalies[0].Anomalies, anomalies[0].Rollover)
+	}
+	if anomalies[1].Anomalies != TEKAnomalyLongLifetime || !anomalies[1].Rollover {
+		t.Errorf("long lifetime has anomalies %d and rollover %v", anomalies[1].Anomalies, anomalies[1].Rollover)
+	}
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// TLV is a single type-length-value element.
+type TLV struct {
+	Type  uint8
+	Value []byte
+	// Children holds the decoded sub-TLVs if the schema declares the type as nested.
+	Children TLVs
+	// Err is set if the value of a nested type couldn't be decoded. The value is kept raw then.
+	Err error
+}
+
+// TLVs is a list of TLV elements in the order they were encoded.
+type TLVs []TLV
+
+// TLVSchema describes how a list of TLVs is encoded.
+type TLVSchema struct {
+	// LengthSize is the size of the length field in bytes, either 1 or 2.
+	LengthSize int
+	// LengthSizes overrides LengthSize for single types.
+	LengthSizes map[uint8]int
+	// Nested maps types containing sub-TLVs to the schema of the sub-TLVs.
+	// Types without an entry are kept as raw value.
+	Nested map[uint8]*TLVSchema
+}
+
+// DecodeTLVs decodes data into a tree of TLVs. A nil schema decodes a flat list with 1 byte lengths.
+// Nested values that can't be decoded don't fail the list, their error is recorded in the TLV.
+func DecodeTLVs(data []byte, schema *TLVSchema) (TLVs, error) {
+	lengthSize := 1
+	if schema != nil && schema.LengthSize != 0 {
+		lengthSize = schema.LengthSize
+	}
+	if lengthSize != 1 && lengthSize != 2 {
+		return nil, fmt.Errorf("tlv length size %d isn't supported", lengthSize)
+	}
+
+	var tlvs TLVs
+	for i := 0; i < len(data); {
+		tlvType := data[i]
+		typeLengthSize, err := schema.typeLengthSize(tlvType, lengthSize)
+		if err != nil {
+			return nil, err
+		}
+		if len(data) < (i + 1 + typeLengthSize) {
+			return nil, fmt.Errorf("tlv header too small")
+		}
+
+		var tlvLen int
+		if typeLengthSize == 1 {
+			tlvLen = int(data[i+1])
+		} else {
+			tlvLen = int(binary.BigEndian.Uint16(data[i+1 : i+3]))
+		}
+
+		valueStart := i + 1 + typeLengthSize
+		if len(data) < (valueStart + tlvLen) {
+			return nil, fmt.Errorf("tlv %d too small", tlvType)
+		}
+
+		tlv := TLV{Type: tlvType, Value: data[valueStart : valueStart+tlvLen]}
+
+		if schema != nil {
+			if nestedSchema, ok := schema.Nested[tlvType]; ok {
+				if nestedSchema == nil {
+					nestedSchema = &TLVSchema{LengthSize: lengthSize}
+				}
+				children, err := DecodeTLVs(tlv.Value, nestedSchema)
+				if err != nil {
+					tlv.Err = fmt.Errorf("tlv %d: %w", tlvType, err)
+				} else {
+					tlv.Children = children
+				}
+			}
+		}
+
+		tlvs = append(tlvs, tlv)
+		i = valueStart + tlvLen
+	}
+
+	return tlvs, nil
+}
+
+// typeLengthSize returns the size of the length field of the type, lengthSize unless the schema overrides it.
+func (schema *TLVSchema) typeLengthSize(tlvType uint8, lengthSize int) (int, error) {
+	if schema == nil {
+		return lengthSize, nil
+	}
+
+	size, ok := schema.LengthSizes[tlvType]
+	if !ok {
+		return lengthSize, nil
+	}
+	if size != 1 && size != 2 {
+		return 0, fmt.Errorf("tlv %d length size %d isn't supported", tlvType, size)
+	}
+	return size, nil
+}
+
+// ParseTLVPath parses a dotted path like "24.8" for use with Get and GetAll.
+func ParseTLVPath(path string) ([]uint8, error) {
+	var result []uint8
+	for _, part := range strings.Split(path, ".") {
+		tlvType, err := strconv.ParseUint(part, 10, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tlv path %q: %w", path, err)
+		}
+		result = append(result, uint8(tlvType))
+	}
+	return result, nil
+}
+
+// Get returns the first TLV matching the path of types.
+func (tlvs TLVs) Get(path ...uint8) (TLV, bool) {
+	if len(path) == 0 {
+		return TLV{}, false
+	}
+
+	for _, tlv := range tlvs {
+		if tlv.Type != path[0] {
+			continue
+		}
+		if len(path) == 1 {
+			return tlv, true
+		}
+		if found, ok := tlv.Children.Get(path[1:]...); ok {
+			return found, true
+		}
+	}
+
+	return TLV{}, false
+}
+
+// GetAll returns all TLVs matching the path of types.
+func (tlvs TLVs) GetAll(path ...uint8) []TLV {
+	if len(path) == 0 {
+		return nil
+	}
+
+	var result []TLV
+	for _, tlv := range tlvs {
+		if tlv.Type != path[0] {
+			continue
+		}
+		if len(path) == 1 {
+			result = append(result, tlv)
+		} else {
+			result = append(result, tlv.Children.GetAll(path[1:]...)...)
+		}
+	}
+
+	return result
+}
+
+// Uint8 returns the value of a TLV with a length of 1.
+func (tlv TLV) Uint8() (uint8, error) {
+	if len(tlv.Value) != 1 {
+		return 0, fmt.Errorf("tlv %d has length %d instead of 1", tlv.Type, len(tlv.Value))
+	}
+	return tlv.Value[0], nil
+}
+
+// Uint16 returns the value of a TLV with a length of 2.
+func (tlv TLV) Uint16() (uint16, error) {
+	if len(tlv.Value) != 2 {
+		return 0, fmt.Errorf("tlv %d has length %d instead of 2", tlv.Type, len(tlv.Value))
+	}
+	return binary.BigEndian.Uint16(tlv.Value), nil
+}
+
+// Uint32 returns the value of a TLV with a length of 4.
+func (tlv TLV) Uint32() (uint32, error) {
+	if len(tlv.Value) != 4 {
+		return 0, fmt.Errorf("tlv %d has length %d instead of 4", tlv.Type, len(tlv.Value))
+	}
+	return binary.BigEndian.Uint32(tlv.Value), nil
+}
+
+// Clone returns a deep copy that doesn't reference the decoded packet data anymore.
+func (tlvs TLVs) Clone() TLVs {
+	if tlvs == nil {
+		return nil
+	}
+
+	result := make(TLVs, len(tlvs))
+	for i, tlv := range tlvs {
+		result[i] = TLV{
+			Type:     tlv.Type,
+			Value:    append([]byte(nil), tlv.Value...),
+			Children: tlv.Children.Clone(),
+			Err:      tlv.Err,
+		}
+	}
+	return result
+}
+
+// Encode serializes the TLVs as described by the schema, the reverse of DecodeTLVs.
+// The value of a TLV with children is encoded from the children.
+func (tlvs TLVs) Encode(schema *TLVSchema) ([]byte, error) {
+	lengthSize := 1
+	if schema != nil && schema.LengthSize != 0 {
+		lengthSize = schema.LengthSize
+	}
+	if lengthSize != 1 && lengthSize != 2 {
+		return nil, fmt.Errorf("tlv length size %d isn't supported", lengthSize)
+	}
+
+	var data []byte
+	for _, tlv := range tlvs {
+		value := tlv.Value
+		if len(tlv.Children) > 0 {
+			var nestedSchema *TLVSchema
+			if schema != nil {
+				nestedSchema = schema.Nested[tlv.Type]
+			}
+			if nestedSchema == nil {
+				nestedSchema = &TLVSchema{LengthSize: lengthSize}
+			}
+
+			var err error
+			if value, err = tlv.Children.Encode(nestedSchema); err != nil {
+				return nil, fmt.Errorf("tlv %d: %w", tlv.Type, err)
+			}
+		}
+
+		typeLengthSize, err := schema.typeLengthSize(tlv.Type, lengthSize)
+		if err != nil {
+			return nil, err
+		}
+
+		data = append(data, tlv.Type)
+		if typeLengthSize == 1 {
+			if len(value) > 0xff {
+				return nil, fmt.Errorf("tlv %d is too long", tlv.Type)
+			}
+			data = append(data, byte(len(value)))
+		} else {
+			if len(value) > 0xffff {
+				return nil, fmt.Errorf("tlv %d is too long", tlv.Type)
+			}
+			data = append(data, byte(len(value)>>8), byte(len(value)))
+		}
+		data = append(data, value...)
+	}
+
+	return data, nil
+}
+
+// uint8TLV returns a TLV with a 1 byte value.
+func uint8TLV(tlvType uint8, value uint8) TLV {
+	return TLV{Type: tlvType, Value: []byte{value}}
+}
+
+// uint16TLV returns a TLV with a 2 byte value.
+func uint16TLV(tlvType uint8, value uint16) TLV {
+	tlv := TLV{Type: tlvType, Value: make([]byte, 2)}
+	binary.BigEndian.PutUint16(tlv.Value, value)
+	return tlv
+}
+
+// uint32TLV returns a TLV with a 4 byte value.
+func uint32TLV(tlvType uint8, value uint32) TLV {
+	tlv := TLV{Type: tlvType, Value: make([]byte, 4)}
+	binary.BigEndian.PutUint32(tlv.Value, value)
+	return tlv
+}
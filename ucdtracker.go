@@ -0,0 +1,80 @@
+package main
+
+import (
+	"sort"
+	"time"
+)
+
+// UpstreamChannel is the latest known configuration of an upstream channel.
+type UpstreamChannel struct {
+	// MessageType is the management message type of the UCD variant.
+	MessageType byte
+	UCD         DOCSISBaseUCD
+	// Changes counts the configuration change count increments seen.
+	Changes   int
+	FirstSeen time.Time
+	LastSeen  time.Time
+}
+
+type upstreamChannelKey struct {
+	upstreamChannelID uint8
+	messageType       byte
+}
+
+// UCDTracker builds the upstream channel plan from the UCDs sent on a downstream.
+type UCDTracker struct {
+	// OnChange is called when the configuration change count of a channel changes.
+	OnChange func(previous, current UpstreamChannel)
+
+	channels map[upstreamChannelKey]*UpstreamChannel
+}
+
+func newUCDTracker() *UCDTracker {
+	return &UCDTracker{channels: make(map[upstreamChannelKey]*UpstreamChannel)}
+}
+
+// Add records a decoded UCD of the given management message type.
+func (tracker *UCDTracker) Add(messageType byte, ucd *DOCSISBaseUCD, captureTime time.Time) {
+	key := upstreamChannelKey{upstreamChannelID: ucd.UpstreamChannelID, messageType: messageType}
+
+	channel, ok := tracker.channels[key]
+	if !ok {
+		tracker.channels[key] = &UpstreamChannel{
+			MessageType: messageType,
+			UCD:         ucd.clone(),
+			FirstSeen:   captureTime,
+			LastSeen:    captureTime,
+		}
+		return
+	}
+
+	channel.LastSeen = captureTime
+	if channel.UCD.ConfigChangeCount == ucd.ConfigChangeCount {
+		return
+	}
+
+	previous := *channel
+	channel.UCD = ucd.clone()
+	channel.Changes++
+
+	if tracker.OnChange != nil {
+		tracker.OnChange(previous, *channel)
+	}
+}
+
+// Plan returns all known upstream channels ordered by channel ID and message type.
+func (tracker *UCDTracker) Plan() []UpstreamChannel {
+	plan := make([]UpstreamChannel, 0, len(tracker.channels))
+	for _, channel := range tracker.channels {
+		plan = append(plan, *channel)
+	}
+
+	sort.Slice(plan, func(i, j int) bool {
+		if plan[i].UCD.UpstreamChannelID != plan[j].UCD.UpstreamChannelID {
+			return plan[i].UCD.UpstreamChannelID < plan[j].UCD.UpstreamChannelID
+		}
+		return plan[i].MessageType < plan[j].MessageType
+	})
+
+	return plan
+}